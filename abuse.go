@@ -0,0 +1,89 @@
+package suss
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Abuse report reasons a visitor can choose from.
+const (
+	AbuseReasonMalware  = "malware"
+	AbuseReasonPhishing = "phishing"
+	AbuseReasonSpam     = "spam"
+	AbuseReasonOther    = "other"
+)
+
+// AbuseReasons lists every reason a link can be reported for.
+var AbuseReasons = []string{
+	AbuseReasonMalware,
+	AbuseReasonPhishing,
+	AbuseReasonSpam,
+	AbuseReasonOther,
+}
+
+// Abuse report statuses. Reports are open until an administrator disables
+// the link or dismisses the report.
+const (
+	AbuseReportOpen      = "open"
+	AbuseReportDisabled  = "disabled"
+	AbuseReportDismissed = "dismissed"
+)
+
+// AbuseReport represents a visitor reporting a short url as malicious.
+type AbuseReport struct {
+	ID         int       `json:"id"`
+	ShortURLID int       `json:"short_url_id"`
+	ShortURL   *ShortURL `json:"short_url,omitempty"`
+
+	Reason  string `json:"reason"`
+	Comment string `json:"comment"`
+
+	// Keyed hash of the reporter's IP address, used to ignore repeated reports.
+	IPHash string `json:"-"`
+
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// Validate returns an error if the report contains invalid fields.
+func (r *AbuseReport) Validate() error {
+	if r.ShortURLID == 0 {
+		return Errorf(EINVALID, "Short url required.")
+	}
+	if !slices.Contains(AbuseReasons, r.Reason) {
+		return Errorf(EINVALID, "Please choose a reason.")
+	}
+	if len(r.Comment) > 1000 {
+		return Errorf(EINVALID, "Comment must be at most 1000 characters.")
+	}
+	return nil
+}
+
+type AbuseReportFilter struct {
+	ID         *int    `json:"id"`
+	ShortURLID *int    `json:"short_url_id"`
+	Status     *string `json:"status"`
+
+	// Restrict to a subset of the results, oldest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// AbuseReportService manages abuse reports. Anyone can report a link while
+// only administrators can review reports.
+type AbuseReportService interface {
+	FindAbuseReports(ctx context.Context, filter AbuseReportFilter) ([]*AbuseReport, int, error)
+
+	// Create reports a short url. Returns an ECONFLICT error if the same
+	// reporter already has an open report for it.
+	Create(ctx context.Context, report *AbuseReport) error
+
+	// Disable disables the short url of a report so it no longer redirects,
+	// closing every open report for it.
+	Disable(ctx context.Context, id int) error
+
+	// Dismiss closes a report without acting on the short url.
+	Dismiss(ctx context.Context, id int) error
+}
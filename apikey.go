@@ -0,0 +1,92 @@
+package suss
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// API key scopes. Keys are limited to the scopes chosen when created.
+const (
+	APIKeyScopeLinksRead     = "links:read"
+	APIKeyScopeLinksWrite    = "links:write"
+	APIKeyScopeAnalyticsRead = "analytics:read"
+)
+
+// APIKeyScopes lists every scope an API key can be granted.
+var APIKeyScopes = []string{
+	APIKeyScopeLinksRead,
+	APIKeyScopeLinksWrite,
+	APIKeyScopeAnalyticsRead,
+}
+
+// APIKey represents a key used to call the API on behalf of a user. Only a
+// hash of the key is stored so the plaintext Key is only available right
+// after Create.
+type APIKey struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	User   *User  `json:"-"`
+	Name   string `json:"name"`
+
+	Key     string `json:"key,omitempty"`
+	KeyHash string `json:"-"`
+
+	// Start of the key, shown to tell keys apart.
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes"`
+
+	// Time the key was last used, zero if never. Updated at most once a minute.
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate returns an error if the key contains invalid fields.
+func (k *APIKey) Validate() error {
+	if k.UserID == 0 {
+		return Errorf(EINVALID, "User required.")
+	}
+	if k.Name == "" {
+		return Errorf(EINVALID, "API key name required.")
+	} else if len(k.Name) > 100 {
+		return Errorf(EINVALID, "API key name must be at most 100 characters.")
+	}
+	if len(k.Scopes) == 0 {
+		return Errorf(EINVALID, "At least one scope required.")
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return Errorf(EINVALID, "Invalid scope %q.", scope)
+		}
+	}
+	return nil
+}
+
+// HasScope returns true if the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// APIKeyFilter filters the API keys of the current user.
+type APIKeyFilter struct {
+	ID *int `json:"id"`
+
+	// Restrict to a subset of the results, newest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// APIKeyService manages the API keys of the user in the context.
+type APIKeyService interface {
+	FindAPIKeys(ctx context.Context, filter APIKeyFilter) ([]*APIKey, int, error)
+
+	// Authenticate returns the API key matching key, along with its user, and
+	// records it as used. Returns an EUNAUTHORIZED error if no key matches.
+	Authenticate(ctx context.Context, key string) (*APIKey, error)
+
+	// Create creates a key for the current user and sets its Key.
+	Create(ctx context.Context, key *APIKey) error
+
+	// Delete revokes a key of the current user.
+	Delete(ctx context.Context, id int) error
+}
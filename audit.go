@@ -0,0 +1,104 @@
+package suss
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Audit event actions, one for each change to a short url.
+const (
+	AuditShortURLCreate          = "short_url.create"
+	AuditShortURLUpdate          = "short_url.update"
+	AuditShortURLRotateSecretKey = "short_url.rotate_secret_key"
+	AuditShortURLDisable         = "short_url.disable"
+	AuditShortURLEnable          = "short_url.enable"
+	AuditShortURLDelete          = "short_url.delete"
+	AuditShortURLFlag            = "short_url.flag"
+	AuditShortURLUnflag          = "short_url.unflag"
+)
+
+// AuditActions lists every audit event action.
+var AuditActions = []string{
+	AuditShortURLCreate,
+	AuditShortURLUpdate,
+	AuditShortURLRotateSecretKey,
+	AuditShortURLDisable,
+	AuditShortURLEnable,
+	AuditShortURLDelete,
+	AuditShortURLFlag,
+	AuditShortURLUnflag,
+}
+
+// Audit event actor types.
+const (
+	AuditActorUser       = "user"
+	AuditActorAPIKey     = "api_key"
+	AuditActorAdminToken = "admin_token"
+	AuditActorAnonymous  = "anonymous"
+
+	// changes made outside of any request, such as the periodic url check
+	AuditActorSystem = "system"
+)
+
+// AuditEvent records who changed a short url, how and when. Events are
+// written in the same transaction as the change they record.
+type AuditEvent struct {
+	ID     int    `json:"id"`
+	Action string `json:"action"`
+
+	// Actor who made the change. Users are identified by ID, along with the
+	// API key they used if any. Every actor has a keyed hash of their IP
+	// address, the only way to tell anonymous actors apart.
+	ActorType     string `json:"actor_type"`
+	ActorUserID   int    `json:"actor_user_id,omitempty"`
+	ActorAPIKeyID int    `json:"actor_api_key_id,omitempty"`
+	ActorIPHash   string `json:"actor_ip_hash,omitempty"`
+
+	// Short url changed. It may have been deleted since.
+	ShortURLID int    `json:"short_url_id"`
+	Slug       string `json:"slug"`
+
+	// JSON snapshots of the short url before and after the change. Before is
+	// null for created short urls and After is null for deleted ones. Secrets
+	// are never included.
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+
+	// ID of the http request which made the change, if any.
+	RequestID string `json:"request_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RequestInfo identifies the http request a change is made by, so it can be
+// recorded in the audit log.
+type RequestInfo struct {
+	ID     string
+	IPHash string
+
+	// Host the request was sent to, used to reject links to the shortener.
+	Host string
+}
+
+type AuditEventFilter struct {
+	Action      *string `json:"action"`
+	ActorType   *string `json:"actor_type"`
+	ActorUserID *int    `json:"actor_user_id"`
+	ShortURLID  *int    `json:"short_url_id"`
+	Slug        *string `json:"slug"`
+	RequestID   *string `json:"request_id"`
+
+	// Filter by date, inclusive of CreatedAfter and exclusive of CreatedBefore.
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+
+	// Restrict to a subset of the results, newest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// AuditService reads the audit log. Only administrators can read it.
+type AuditService interface {
+	FindAuditEvents(ctx context.Context, filter AuditEventFilter) ([]*AuditEvent, int, error)
+}
@@ -0,0 +1,60 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// MaxClickFieldLength is the maximum length in bytes of the referrer and user
+// agent stored with a click. Both are sent by the visitor, longer values are
+// truncated.
+const MaxClickFieldLength = 512
+
+// Click represents a single visit of a short url.
+type Click struct {
+	ID         int `json:"id"`
+	ShortURLID int `json:"short_url_id"`
+
+	// Request details captured at redirect time. The visitor's IP address is
+	// never stored, only a keyed hash of it used to count unique visitors.
+	Referrer  string `json:"referrer"`
+	UserAgent string `json:"user_agent"`
+	IPHash    string `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *Click) Validate() error {
+	if c.ShortURLID == 0 {
+		return Errorf(EINVALID, "Short url required.")
+	}
+	return nil
+}
+
+// ClickStats represents aggregated analytics for a short url.
+type ClickStats struct {
+	Total  int `json:"total"`
+	Unique int `json:"unique"`
+
+	// Time of the most recent click. Zero if never visited.
+	LastClickedAt time.Time `json:"last_clicked_at"`
+}
+
+type ClickFilter struct {
+	ShortURLID *int `json:"short_url_id"`
+
+	// Restrict to a subset of the results, newest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type ClickService interface {
+	FindClicks(ctx context.Context, filter ClickFilter) ([]*Click, int, error)
+	FindClickStats(ctx context.Context, shortURLID int) (*ClickStats, error)
+
+	// Create records a click, unless the short url has already been visited
+	// its maximum number of times. The count is checked in the same statement
+	// as the insert so concurrent visits cannot go past the maximum, an
+	// ECONFLICT error is returned instead.
+	Create(ctx context.Context, click *Click) error
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/heyjorgedev/suss"
 	"github.com/heyjorgedev/suss/http"
+	"github.com/heyjorgedev/suss/smtp"
 	"github.com/heyjorgedev/suss/sqlite"
+	"github.com/heyjorgedev/suss/threatlist"
 )
 
 func main() {
@@ -21,6 +28,19 @@ func main() {
 
 	p := NewProgram()
 
+	// run a one-off command instead of the server, e.g. "suss reencrypt"
+	if len(os.Args) > 1 {
+		err := p.RunCommand(ctx, os.Args[1:])
+		if closeErr := p.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	if err := p.Run(ctx); err != nil {
 		p.Close()
 		fmt.Fprintln(os.Stderr, err)
@@ -38,8 +58,13 @@ func main() {
 }
 
 type Config struct {
-	EncryptionKey string
-	HashKey       string
+	// hex encoded keys. the encryption key encrypts session cookies as is, so
+	// it must decode to 16, 24 or 32 bytes, and the database derives its own
+	// AES-256 key from it. previous encryption keys are only used to decrypt
+	// database values while rotating, so any length works for them.
+	EncryptionKey          string
+	PreviousEncryptionKeys []string
+	HashKey                string
 
 	DB struct {
 		DSN string
@@ -48,6 +73,56 @@ type Config struct {
 	HTTP struct {
 		Hostname string
 		Port     int
+
+		// public hosts the shortener is served from, links to these are rejected
+		PublicHosts []string
+
+		// public base url of the shortener, e.g. "https://example.com", used to
+		// build links sent by email. Required to send email.
+		PublicURL string
+	}
+
+	URL struct {
+		Schemes       []string
+		MaxLength     int
+		StripFragment bool
+	}
+
+	// smtp server used to send manage links, email is disabled without an address
+	SMTP struct {
+		Addr     string
+		Username string
+		Password string
+		From     string
+	}
+
+	// token granting access to /admin without an administrator account, only
+	// meant to set up an instance, disabled if empty
+	Admin struct {
+		Token string
+	}
+
+	// openid connect provider used for single sign-on, disabled without an issuer
+	OIDC struct {
+		Issuer         string
+		ClientID       string
+		ClientSecret   string
+		AllowedDomains []string
+	}
+
+	// local threat list destinations are screened against, disabled without a
+	// path. existing links are screened again every interval.
+	URLCheck struct {
+		ThreatList string
+		Interval   time.Duration
+	}
+
+	// proof-of-work challenge anonymous visitors solve to create links, as
+	// leading zero bits, disabled if zero. the difficulty rises by a bit each
+	// time anonymous creations per minute double past the spike threshold.
+	Challenge struct {
+		Difficulty     int
+		SpikeThreshold int
 	}
 }
 
@@ -65,6 +140,20 @@ func DefaultConfig() *Config {
 	config.HTTP.Hostname = "0.0.0.0"
 	config.HTTP.Port = 8080
 
+	// destination urls
+	config.URL.Schemes = suss.DefaultURLPolicy.Schemes
+	config.URL.MaxLength = suss.DefaultURLPolicy.MaxLength
+	config.URL.StripFragment = suss.DefaultURLPolicy.StripFragment
+
+	// email
+	config.SMTP.From = "SuSS <noreply@localhost>"
+
+	// url checks
+	config.URLCheck.Interval = time.Hour
+
+	// challenges
+	config.Challenge.SpikeThreshold = 30
+
 	return config
 }
 
@@ -83,6 +172,10 @@ func GetConfigFromEnv() (*Config, error) {
 		config.EncryptionKey = encryptionKey
 	}
 
+	if previousEncryptionKeys := os.Getenv("PREVIOUS_ENCRYPTION_KEYS"); previousEncryptionKeys != "" {
+		config.PreviousEncryptionKeys = splitList(previousEncryptionKeys)
+	}
+
 	// configure database
 	dsn := os.Getenv("DB_DSN")
 	if dsn != "" {
@@ -103,9 +196,109 @@ func GetConfigFromEnv() (*Config, error) {
 		config.HTTP.Port = portInt
 	}
 
+	if publicHosts := os.Getenv("PUBLIC_HOSTS"); publicHosts != "" {
+		config.HTTP.PublicHosts = splitList(publicHosts)
+	}
+	config.HTTP.PublicURL = strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+
+	// configure destination url validation
+	if schemes := os.Getenv("URL_SCHEMES"); schemes != "" {
+		config.URL.Schemes = splitList(schemes)
+	}
+
+	if maxLength := os.Getenv("URL_MAX_LENGTH"); maxLength != "" {
+		maxLengthInt, err := strconv.Atoi(maxLength)
+		if err != nil {
+			return config, fmt.Errorf("invalid url max length: %w", err)
+		}
+		config.URL.MaxLength = maxLengthInt
+	}
+
+	if stripFragment := os.Getenv("URL_STRIP_FRAGMENT"); stripFragment != "" {
+		stripFragmentBool, err := strconv.ParseBool(stripFragment)
+		if err != nil {
+			return config, fmt.Errorf("invalid url strip fragment: %w", err)
+		}
+		config.URL.StripFragment = stripFragmentBool
+	}
+
+	// configure outgoing email
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		config.SMTP.Addr = addr
+	}
+	config.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	config.SMTP.Password = os.Getenv("SMTP_PASSWORD")
+	if from := os.Getenv("SMTP_FROM"); from != "" {
+		config.SMTP.From = from
+	}
+
+	// configure admin access
+	config.Admin.Token = os.Getenv("ADMIN_TOKEN")
+
+	// configure single sign-on
+	config.OIDC.Issuer = os.Getenv("OIDC_ISSUER")
+	config.OIDC.ClientID = os.Getenv("OIDC_CLIENT_ID")
+	config.OIDC.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+	if allowedDomains := os.Getenv("OIDC_ALLOWED_DOMAINS"); allowedDomains != "" {
+		config.OIDC.AllowedDomains = splitList(allowedDomains)
+	}
+
+	// configure url threat checks
+	config.URLCheck.ThreatList = os.Getenv("URL_THREAT_LIST")
+	if interval := os.Getenv("URL_CHECK_INTERVAL"); interval != "" {
+		intervalDuration, err := time.ParseDuration(interval)
+		if err != nil {
+			return config, fmt.Errorf("invalid url check interval: %w", err)
+		} else if intervalDuration <= 0 {
+			return config, fmt.Errorf("invalid url check interval: must be positive")
+		}
+		config.URLCheck.Interval = intervalDuration
+	}
+
+	// configure link creation challenges
+	if difficulty := os.Getenv("CHALLENGE_DIFFICULTY"); difficulty != "" {
+		difficultyInt, err := strconv.Atoi(difficulty)
+		if err != nil {
+			return config, fmt.Errorf("invalid challenge difficulty: %w", err)
+		} else if difficultyInt < 0 || difficultyInt > http.MaxChallengeDifficulty {
+			return config, fmt.Errorf("invalid challenge difficulty: must be between 0 and %d", http.MaxChallengeDifficulty)
+		}
+		config.Challenge.Difficulty = difficultyInt
+	}
+
+	if threshold := os.Getenv("CHALLENGE_SPIKE_THRESHOLD"); threshold != "" {
+		thresholdInt, err := strconv.Atoi(threshold)
+		if err != nil {
+			return config, fmt.Errorf("invalid challenge spike threshold: %w", err)
+		} else if thresholdInt < 0 {
+			return config, fmt.Errorf("invalid challenge spike threshold: must not be negative")
+		}
+		config.Challenge.SpikeThreshold = thresholdInt
+	}
+
 	return config, nil
 }
 
+// randomHexKey returns a hex encoded random 32 byte key.
+func randomHexKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+// splitList splits a comma separated environment value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 type Program struct {
 	// configuration
 	Config *Config
@@ -117,7 +310,20 @@ type Program struct {
 	HTTPServer *http.Server
 
 	// services
-	ShortURLService suss.ShortURLService
+	AbuseReportService suss.AbuseReportService
+	APIKeyService      suss.APIKeyService
+	AuditService       suss.AuditService
+	ClickService       suss.ClickService
+	DomainRuleService  suss.DomainRuleService
+	IdentityService    suss.IdentityService
+	SessionService     suss.SessionService
+	ShortURLService    suss.ShortURLService
+	StatsService       suss.StatsService
+	UserService        suss.UserService
+	WorkspaceService   suss.WorkspaceService
+
+	// optional mailer, nil unless SMTP is configured
+	Mailer suss.Mailer
 }
 
 func NewProgram() *Program {
@@ -129,33 +335,224 @@ func NewProgram() *Program {
 }
 
 func (p *Program) Run(ctx context.Context) error {
-	// load the config from the environment
-	config, err := GetConfigFromEnv()
-	if err != nil {
-		return fmt.Errorf("cannot load config: %w", err)
+	if err := p.configure(); err != nil {
+		return err
 	}
-	p.Config = config
 
 	// open the database, configure it and migrate to latest version
-	p.DB.DSN = p.Config.DB.DSN
-	if err := p.DB.Open(); err != nil {
-		return fmt.Errorf("cannot open db: %w", err)
+	if err := p.openDB(); err != nil {
+		return err
 	}
 
 	// initialize services
+	p.AbuseReportService = sqlite.NewAbuseReportService(p.DB)
+	p.APIKeyService = sqlite.NewAPIKeyService(p.DB)
+	p.AuditService = sqlite.NewAuditService(p.DB)
+	p.ClickService = sqlite.NewClickService(p.DB)
+	p.DomainRuleService = sqlite.NewDomainRuleService(p.DB)
+	p.IdentityService = sqlite.NewIdentityService(p.DB)
+	p.SessionService = sqlite.NewSessionService(p.DB)
 	p.ShortURLService = sqlite.NewShortURLService(p.DB)
+	p.StatsService = sqlite.NewStatsService(p.DB)
+	p.UserService = sqlite.NewUserService(p.DB)
+	p.WorkspaceService = sqlite.NewWorkspaceService(p.DB)
+
+	if p.Config.SMTP.Addr != "" {
+		mailer := smtp.NewMailer(p.Config.SMTP.Addr, p.Config.SMTP.From)
+		mailer.Username = p.Config.SMTP.Username
+		mailer.Password = p.Config.SMTP.Password
+		p.Mailer = mailer
+	}
 
 	// bind services to http server
+	p.HTTPServer.AbuseReportService = p.AbuseReportService
+	p.HTTPServer.APIKeyService = p.APIKeyService
+	p.HTTPServer.AuditService = p.AuditService
+	p.HTTPServer.ClickService = p.ClickService
+	p.HTTPServer.DomainRuleService = p.DomainRuleService
+	p.HTTPServer.IdentityService = p.IdentityService
+	p.HTTPServer.SessionService = p.SessionService
 	p.HTTPServer.ShortURLService = p.ShortURLService
+	p.HTTPServer.StatsService = p.StatsService
+	p.HTTPServer.UserService = p.UserService
+	p.HTTPServer.WorkspaceService = p.WorkspaceService
+	p.HTTPServer.Mailer = p.Mailer
+	p.HTTPServer.BaseURL = p.Config.HTTP.PublicURL
 
 	// configure http server
 	p.HTTPServer.Addr = fmt.Sprintf("%s:%d", p.Config.HTTP.Hostname, p.Config.HTTP.Port)
+	p.HTTPServer.HashKey = p.Config.HashKey
+	p.HTTPServer.EncryptionKey = p.Config.EncryptionKey
+	p.HTTPServer.OIDCIssuer = p.Config.OIDC.Issuer
+	p.HTTPServer.OIDCClientID = p.Config.OIDC.ClientID
+	p.HTTPServer.OIDCClientSecret = p.Config.OIDC.ClientSecret
+	p.HTTPServer.OIDCAllowedDomains = p.Config.OIDC.AllowedDomains
+	p.HTTPServer.AdminToken = p.Config.Admin.Token
+	p.HTTPServer.ChallengeDifficulty = p.Config.Challenge.Difficulty
+	p.HTTPServer.ChallengeSpikeThreshold = p.Config.Challenge.SpikeThreshold
 
 	// start the http server
 	if err := p.HTTPServer.Open(); err != nil {
 		return err
 	}
 
+	// screen existing links again as the threat list changes
+	if p.DB.URLChecker != nil {
+		go p.checkURLs(ctx, p.Config.URLCheck.Interval)
+	}
+
+	return nil
+}
+
+// checkURLs screens the destination of every link each interval until ctx is
+// done.
+func (p *Program) checkURLs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flagged, err := p.DB.CheckShortURLs(ctx)
+			if err != nil {
+				log.Printf("cannot check urls: %s", err)
+				continue
+			}
+			log.Printf("checked urls, %d flagged", flagged)
+		}
+	}
+}
+
+// RunCommand runs a one-off maintenance command against the database.
+func (p *Program) RunCommand(ctx context.Context, args []string) error {
+	if err := p.configure(); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "reencrypt":
+		// re-encrypt data with the current key after rotating ENCRYPTION_KEY
+		if err := p.openDB(); err != nil {
+			return err
+		}
+		n, err := p.DB.Reencrypt(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot re-encrypt data: %w", err)
+		}
+		fmt.Printf("re-encrypted %d values\n", n)
+		return nil
+	case "admin":
+		// grant the admin role to a registered user, e.g. "suss admin ann@example.com"
+		if len(args) != 2 {
+			return fmt.Errorf("usage: suss admin EMAIL")
+		}
+		if err := p.openDB(); err != nil {
+			return err
+		}
+		users := sqlite.NewUserService(p.DB)
+		found, _, err := users.FindUsers(ctx, suss.UserFilter{Email: &args[1]})
+		if err != nil {
+			return err
+		} else if len(found) == 0 {
+			return fmt.Errorf("no user with email %q", args[1])
+		}
+		isAdmin := true
+		if _, err := users.Update(ctx, found[0].ID, suss.UserUpdate{IsAdmin: &isAdmin}); err != nil {
+			return fmt.Errorf("cannot grant admin role: %w", err)
+		}
+		fmt.Printf("%s is now an administrator\n", found[0].Email)
+		return nil
+	case "check-urls":
+		// screen every destination against the threat list right away
+		if p.DB.URLChecker == nil {
+			return fmt.Errorf("URL_THREAT_LIST is required to check urls")
+		}
+		if err := p.openDB(); err != nil {
+			return err
+		}
+		flagged, err := p.DB.CheckShortURLs(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot check urls: %w", err)
+		}
+		fmt.Printf("checked urls, %d flagged\n", flagged)
+		return nil
+	default:
+		return fmt.Errorf("unknown command: %q", args[0])
+	}
+}
+
+// configure loads the config from the environment and applies global settings.
+func (p *Program) configure() error {
+	// load the config from the environment
+	config, err := GetConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+	p.Config = config
+
+	// in-memory databases are lost on exit so throwaway keys are fine, anything
+	// persistent needs stable keys or stored secrets become unusable
+	if p.Config.HashKey == "" || p.Config.EncryptionKey == "" {
+		if p.Config.DB.DSN != ":memory:" {
+			return fmt.Errorf("HASH_KEY and ENCRYPTION_KEY are required when using a persistent database")
+		}
+		if p.Config.HashKey == "" {
+			p.Config.HashKey = randomHexKey()
+		}
+		if p.Config.EncryptionKey == "" {
+			p.Config.EncryptionKey = randomHexKey()
+		}
+	}
+
+	// emailed links cannot be built from request headers, which anyone can set
+	if p.Config.SMTP.Addr != "" && p.Config.HTTP.PublicURL == "" {
+		return fmt.Errorf("PUBLIC_URL is required to send email")
+	}
+
+	// screen destinations against the threat list, if any
+	if p.Config.URLCheck.ThreatList != "" {
+		checker := threatlist.NewURLChecker(p.Config.URLCheck.ThreatList)
+		if err := checker.Open(); err != nil {
+			return fmt.Errorf("cannot open threat list: %w", err)
+		}
+		p.DB.URLChecker = checker
+	}
+
+	// configure which destination urls are accepted
+	suss.DefaultURLPolicy = suss.URLPolicy{
+		Schemes:       p.Config.URL.Schemes,
+		MaxLength:     p.Config.URL.MaxLength,
+		StripFragment: p.Config.URL.StripFragment,
+		Hosts:         p.Config.HTTP.PublicHosts,
+	}
+
+	return nil
+}
+
+// openDB opens the database with the configured keys and migrates it.
+func (p *Program) openDB() (err error) {
+	p.DB.DSN = p.Config.DB.DSN
+
+	if p.DB.HashKey, err = hex.DecodeString(p.Config.HashKey); err != nil {
+		return fmt.Errorf("invalid hash key, expected hex: %w", err)
+	}
+	if p.DB.EncryptionKey, err = hex.DecodeString(p.Config.EncryptionKey); err != nil {
+		return fmt.Errorf("invalid encryption key, expected hex: %w", err)
+	}
+	p.DB.PreviousEncryptionKeys = nil
+	for _, v := range p.Config.PreviousEncryptionKeys {
+		key, err := hex.DecodeString(v)
+		if err != nil {
+			return fmt.Errorf("invalid previous encryption key, expected hex: %w", err)
+		}
+		p.DB.PreviousEncryptionKeys = append(p.DB.PreviousEncryptionKeys, key)
+	}
+
+	if err := p.DB.Open(); err != nil {
+		return fmt.Errorf("cannot open db: %w", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,62 @@
+package suss
+
+import "context"
+
+// contextKey represents an internal key for adding context fields.
+type contextKey int
+
+const (
+	// userContextKey stores the current signed in user.
+	userContextKey = contextKey(iota + 1)
+
+	// apiKeyContextKey stores the API key used to authenticate the request.
+	apiKeyContextKey
+
+	// requestInfoContextKey stores the http request changes are made by.
+	requestInfoContextKey
+)
+
+// NewContextWithUser returns a new context with the given user.
+func NewContextWithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the current signed in user, or nil if anonymous.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userContextKey).(*User)
+	return user
+}
+
+// UserIDFromContext is a helper function that returns the ID of the current
+// signed in user, or zero if anonymous.
+func UserIDFromContext(ctx context.Context) int {
+	if user := UserFromContext(ctx); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// NewContextWithAPIKey returns a new context with the given API key and its user.
+func NewContextWithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	ctx = NewContextWithUser(ctx, key.User)
+	return context.WithValue(ctx, apiKeyContextKey, key)
+}
+
+// APIKeyFromContext returns the API key used to authenticate, or nil if the
+// request was not authenticated with an API key.
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyContextKey).(*APIKey)
+	return key
+}
+
+// NewContextWithRequestInfo returns a new context with the given request info.
+func NewContextWithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey, info)
+}
+
+// RequestInfoFromContext returns the http request changes are made by, or nil
+// outside of http requests.
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoContextKey).(*RequestInfo)
+	return info
+}
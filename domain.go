@@ -0,0 +1,97 @@
+package suss
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// Domain rule actions. Blocked hosts are always rejected. Once any host is
+// allowed, only allowed hosts are accepted.
+const (
+	DomainRuleAllow = "allow"
+	DomainRuleBlock = "block"
+)
+
+// DomainRule allows or blocks short urls to a host. A pattern such as
+// "*.example.com" matches example.com and all of its subdomains.
+type DomainRule struct {
+	ID      int    `json:"id"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate returns an error if the rule contains invalid fields. The pattern
+// is normalized in place.
+func (r *DomainRule) Validate() error {
+	if r.Action != DomainRuleAllow && r.Action != DomainRuleBlock {
+		return Errorf(EINVALID, "Invalid action %q.", r.Action)
+	}
+
+	pattern := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(r.Pattern)), ".")
+	domain, wildcard := strings.CutPrefix(pattern, "*.")
+	if domain == "" {
+		return Errorf(EINVALID, "Domain required.")
+	} else if net.ParseIP(domain) == nil {
+		var err error
+		if domain, err = idna.Lookup.ToASCII(domain); err != nil || strings.Contains(domain, "*") {
+			return Errorf(EINVALID, "Invalid domain %q.", r.Pattern)
+		}
+	} else if wildcard {
+		return Errorf(EINVALID, "IP addresses cannot have subdomains.")
+	}
+
+	if r.Pattern = domain; wildcard {
+		r.Pattern = "*." + domain
+	}
+	return nil
+}
+
+// Matches returns true if host, a lowercase host name without port, matches
+// the pattern of the rule.
+func (r *DomainRule) Matches(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if domain, ok := strings.CutPrefix(r.Pattern, "*."); ok {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == r.Pattern
+}
+
+// AllowsHost returns true if rules accept short urls to host.
+func AllowsHost(rules []*DomainRule, host string) bool {
+	allowed, hasAllowRules := false, false
+	for _, rule := range rules {
+		switch rule.Action {
+		case DomainRuleBlock:
+			if rule.Matches(host) {
+				return false
+			}
+		case DomainRuleAllow:
+			hasAllowRules = true
+			allowed = allowed || rule.Matches(host)
+		}
+	}
+	return allowed || !hasAllowRules
+}
+
+type DomainRuleFilter struct {
+	ID     *int    `json:"id"`
+	Action *string `json:"action"`
+
+	// Restrict to a subset of the results, sorted by pattern.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// DomainRuleService manages the domain rules. Only administrators can change
+// them, while anyone can read them to check a destination.
+type DomainRuleService interface {
+	FindDomainRules(ctx context.Context, filter DomainRuleFilter) ([]*DomainRule, int, error)
+	Create(ctx context.Context, rule *DomainRule) error
+	Delete(ctx context.Context, id int) error
+}
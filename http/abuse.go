@@ -0,0 +1,164 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// AbuseReportsPageSize is the number of reports listed per page in the
+// moderation queue.
+const AbuseReportsPageSize = 20
+
+// AbuseReportRecentClicks is the number of recent clicks shown with each report.
+const AbuseReportRecentClicks = 5
+
+func (s *Server) handlerReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortUrl, err := s.ShortURLService.FindDialBySlug(r.Context(), chi.URLParam(r, "slug"))
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		html.ReportPage(html.ReportPageProps{
+			ShortURL: shortUrl,
+		}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerReportSubmit() http.HandlerFunc {
+	rateLimiter := httprate.NewRateLimiter(5, time.Minute, httprate.WithKeyByIP())
+	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shortUrl, err := s.ShortURLService.FindDialBySlug(r.Context(), chi.URLParam(r, "slug"))
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		report := &suss.AbuseReport{
+			ShortURLID: shortUrl.ID,
+			Reason:     r.PostFormValue("reason"),
+			Comment:    strings.TrimSpace(r.PostFormValue("comment")),
+			IPHash:     s.hash(s.RemoteIP(r)),
+		}
+		if err := s.AbuseReportService.Create(r.Context(), report); suss.ErrorCode(err) == suss.EINVALID {
+			w.WriteHeader(http.StatusBadRequest)
+			html.ReportPage(html.ReportPageProps{
+				ShortURL: shortUrl,
+				Reason:   report.Reason,
+				Comment:  report.Comment,
+				Error:    suss.ErrorMessage(err),
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil && suss.ErrorCode(err) != suss.ECONFLICT {
+			s.Error(w, r, err)
+			return
+		}
+
+		// repeated reports are acknowledged the same way as the first one
+		html.ReportPage(html.ReportPageProps{
+			ShortURL:  shortUrl,
+			Submitted: true,
+		}).Render(r.Context(), w)
+	}))
+
+	return handler.ServeHTTP
+}
+
+func (s *Server) handlerAdminAbuseReports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = suss.AbuseReportOpen
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if offset < 0 {
+			offset = 0
+		}
+
+		reports, n, err := s.AbuseReportService.FindAbuseReports(r.Context(), suss.AbuseReportFilter{
+			Status: &status,
+			Offset: offset,
+			Limit:  AbuseReportsPageSize,
+		})
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		items := make([]html.AdminAbuseReport, len(reports))
+		for i, report := range reports {
+			stats, err := s.ClickService.FindClickStats(r.Context(), report.ShortURLID)
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			clicks, _, err := s.ClickService.FindClicks(r.Context(), suss.ClickFilter{
+				ShortURLID: &report.ShortURLID,
+				Limit:      AbuseReportRecentClicks,
+			})
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			items[i] = html.AdminAbuseReport{
+				Report:       report,
+				Url:          report.ShortURL.ShortURL(s.PublicURL(r)),
+				Stats:        stats,
+				RecentClicks: clicks,
+			}
+		}
+
+		html.AdminAbuseReportsPage(html.AdminAbuseReportsPageProps{
+			Reports: items,
+			Status:  status,
+			N:       n,
+			Offset:  offset,
+			Limit:   AbuseReportsPageSize,
+		}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerAdminAbuseReportDisable() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Abuse report not found."))
+			return
+		}
+
+		if err := s.AbuseReportService.Disable(r.Context(), id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerAdminAbuseReportDismiss() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Abuse report not found."))
+			return
+		}
+
+		if err := s.AbuseReportService.Dismiss(r.Context(), id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/reports", http.StatusSeeOther)
+	}
+}
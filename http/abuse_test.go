@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestServer_Report(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		shortUrl := &suss.ShortURL{LongURL: "https://phish.example/login"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		// visitors report the link, repeated reports are acknowledged alike
+		c := s.NewClient(t)
+		for i := 0; i < 2; i++ {
+			if resp, body := c.PostForm("/report/"+shortUrl.Slug, url.Values{"reason": {suss.AbuseReasonPhishing}}); resp.StatusCode != http.StatusOK {
+				t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+			}
+		}
+
+		// the report is queued for administrators
+		admin := s.NewClient(t)
+		admin.MustLoginAdmin()
+		reports, n, err := s.AbuseReportService.FindAbuseReports(adminContext(), suss.AbuseReportFilter{})
+		if err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d", n)
+		}
+		if resp, body := admin.Get("/admin/reports"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "phish.example") {
+			t.Fatalf("report not listed: %s", body)
+		}
+
+		// disabling the link stops its redirect
+		if resp, _ := admin.PostForm(fmt.Sprintf("/admin/reports/%d/disable", reports[0].ID), url.Values{}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, body := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusGone {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if strings.Contains(body, "phish.example") {
+			t.Fatalf("destination revealed: %s", body)
+		}
+	})
+
+	t.Run("ErrReason", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+		if resp, _ := s.NewClient(t).PostForm("/report/"+shortUrl.Slug, url.Values{"comment": {"bad"}}); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrNotAdmin", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		c := s.NewClient(t)
+		if resp, _ := c.Get("/admin/reports"); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got := resp.Header.Get("Location"); !strings.HasPrefix(got, "/admin/login?") {
+			t.Fatalf("Location=%q", got)
+		}
+		if resp, _ := c.PostForm("/admin/reports/1/disable", url.Values{}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, _ := c.PostForm("/admin/login", url.Values{"token": {"bogus"}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// AdminShortURLsPageSize is the number of links listed per page on /admin.
+const AdminShortURLsPageSize = 50
+
+// AdminStatsDays is the number of days of activity charted on /admin.
+const AdminStatsDays = 30
+
+func (s *Server) registerAdminRoutes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/login", s.handlerAdminLogin())
+		r.Post("/login", s.handlerAdminLoginSubmit())
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.middlewareRequireAdmin)
+			r.Get("/", s.handlerAdminDashboard())
+			r.Post("/links", s.handlerAdminShortUrlsBulk())
+			r.Get("/reports", s.handlerAdminAbuseReports())
+			r.Post("/reports/{id}/disable", s.handlerAdminAbuseReportDisable())
+			r.Post("/reports/{id}/dismiss", s.handlerAdminAbuseReportDismiss())
+			r.Get("/domains", s.handlerAdminDomainRules())
+			r.Post("/domains", s.handlerAdminDomainRuleCreate())
+			r.Delete("/domains/{id}", s.handlerAdminDomainRuleDelete())
+			r.Get("/audit", s.handlerAdminAuditEvents())
+			r.Get("/audit/export", s.handlerAdminAuditExport())
+		})
+	})
+}
+
+// middlewareRequireAdmin only lets administrators, or visitors signed in with
+// the admin token, through. Visitors who are not signed in are sent to the
+// login page first.
+func (s *Server) middlewareRequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := suss.UserFromContext(r.Context())
+		if (user == nil || !user.IsAdmin) && s.hasAdminTokenSession(r) {
+			// the token stands in for an administrator account, so services
+			// authorize it as one
+			user = &suss.User{Name: "Admin token", IsAdmin: true}
+			r = r.WithContext(suss.NewContextWithUser(r.Context(), user))
+		}
+
+		if user == nil && r.Method == http.MethodGet {
+			login := "/login"
+			if s.AdminToken != "" {
+				login = "/admin/login"
+			}
+			http.Redirect(w, r, login+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+			return
+		} else if user == nil || !user.IsAdmin {
+			s.Error(w, r, suss.Errorf(suss.EUNAUTHORIZED, "You must be an administrator to do this."))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasAdminTokenSession returns true if the visitor signed in with the current
+// admin token. Changing the token signs everyone out.
+func (s *Server) hasAdminTokenSession(r *http.Request) bool {
+	v := s.session(r).AdminTokenHash
+	return s.AdminToken != "" && v != "" && subtle.ConstantTimeCompare([]byte(v), []byte(s.hash(s.AdminToken))) == 1
+}
+
+func (s *Server) handlerAdminLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next := localRedirect(r.URL.Query().Get("next"))
+		if s.AdminToken == "" {
+			http.Redirect(w, r, "/login?next="+url.QueryEscape(next), http.StatusSeeOther)
+			return
+		}
+
+		html.AdminLoginPage(html.AdminLoginPageProps{
+			Next: next,
+		}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerAdminLoginSubmit() http.HandlerFunc {
+	rateLimiter := httprate.NewRateLimiter(5, time.Minute, httprate.WithKeyByIP())
+	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := localRedirect(r.PostFormValue("next"))
+
+		token := r.PostFormValue("token")
+		if s.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			html.AdminLoginPage(html.AdminLoginPageProps{
+				Next:  next,
+				Error: "The admin token is incorrect.",
+			}).Render(r.Context(), w)
+			return
+		}
+
+		cookie := s.session(r)
+		cookie.AdminTokenHash = s.hash(s.AdminToken)
+		if err := s.setSession(w, r, cookie); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	}))
+
+	return handler.ServeHTTP
+}
+
+func (s *Server) handlerAdminDashboard() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		props := html.AdminDashboardPageProps{
+			Query: strings.TrimSpace(r.URL.Query().Get("q")),
+			Host:  strings.TrimSpace(r.URL.Query().Get("host")),
+			Limit: AdminShortURLsPageSize,
+			Next:  r.URL.RequestURI(),
+		}
+		props.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+		if props.Offset < 0 {
+			props.Offset = 0
+		}
+
+		stats, err := s.StatsService.FindInstanceStats(r.Context(), AdminStatsDays)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+		props.Stats = stats
+
+		filter := suss.ShortURLFilter{
+			Offset: props.Offset,
+			Limit:  props.Limit,
+		}
+		if props.Query != "" {
+			filter.LongURL = &props.Query
+		}
+		if props.Host != "" {
+			filter.Host = &props.Host
+		}
+
+		shortUrls, n, err := s.ShortURLService.FindShortUrls(r.Context(), filter)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+		props.N = n
+
+		props.ShortURLs = make([]html.HomepageShortURL, len(shortUrls))
+		for i, shortUrl := range shortUrls {
+			clicks, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			props.ShortURLs[i] = html.HomepageShortURL{
+				Url:       shortUrl.ShortURL(s.PublicURL(r)),
+				ManageURL: fmt.Sprintf("/manage/%s", shortUrl.Slug),
+				ShortURL:  shortUrl,
+				Visits:    clicks.Total,
+			}
+		}
+
+		html.AdminDashboardPage(props).Render(r.Context(), w)
+	}
+}
+
+// handlerAdminShortUrlsBulk disables, enables or deletes the selected links.
+func (s *Server) handlerAdminShortUrlsBulk() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Invalid form."))
+			return
+		}
+
+		ids := make([]int, 0, len(r.PostForm["id"]))
+		for _, v := range r.PostForm["id"] {
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Short Url not found."))
+				return
+			}
+			ids = append(ids, id)
+		}
+
+		// links deleted in the meantime are skipped, any other error leaves
+		// every link unchanged
+		if _, err := s.ShortURLService.BulkApply(r.Context(), r.PostForm.Get("action"), ids); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, localRedirect(r.PostForm.Get("next")), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerAdminDomainRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.renderAdminDomainRulesPage(w, r, html.AdminDomainRulesPageProps{
+			Action: suss.DomainRuleBlock,
+		})
+	}
+}
+
+func (s *Server) handlerAdminDomainRuleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rule := &suss.DomainRule{
+			Pattern: r.PostFormValue("pattern"),
+			Action:  r.PostFormValue("action"),
+		}
+		if err := s.DomainRuleService.Create(r.Context(), rule); suss.ErrorCode(err) == suss.EINVALID || suss.ErrorCode(err) == suss.ECONFLICT {
+			w.WriteHeader(ErrorStatusCode(suss.ErrorCode(err)))
+			s.renderAdminDomainRulesPage(w, r, html.AdminDomainRulesPageProps{
+				Pattern: rule.Pattern,
+				Action:  rule.Action,
+				Error:   suss.ErrorMessage(err),
+			})
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/domains", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerAdminDomainRuleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Domain rule not found."))
+			return
+		}
+
+		if err := s.DomainRuleService.Delete(r.Context(), id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/admin/domains", http.StatusSeeOther)
+	}
+}
+
+// renderAdminDomainRulesPage renders every domain rule along with props.
+func (s *Server) renderAdminDomainRulesPage(w http.ResponseWriter, r *http.Request, props html.AdminDomainRulesPageProps) {
+	rules, _, err := s.DomainRuleService.FindDomainRules(r.Context(), suss.DomainRuleFilter{})
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	props.Rules = rules
+	html.AdminDomainRulesPage(props).Render(r.Context(), w)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+const testAdminToken = "admin-token"
+
+// MustLoginAdmin signs the client in to the admin area with the admin token.
+func (c *Client) MustLoginAdmin() {
+	c.tb.Helper()
+	resp, body := c.PostForm("/admin/login", url.Values{"token": {testAdminToken}})
+	if resp.StatusCode != http.StatusSeeOther {
+		c.tb.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestServer_AdminDashboard(t *testing.T) {
+	t.Run("Search", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		for _, longURL := range []string{"https://example.com/needle", "https://example.com/haystack"} {
+			if err := s.ShortURLService.Create(context.Background(), &suss.ShortURL{LongURL: longURL}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		c := s.NewClient(t)
+		c.MustLoginAdmin()
+		if resp, body := c.Get("/admin/?q=needle"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "https://example.com/needle") || strings.Contains(body, "https://example.com/haystack") {
+			t.Fatalf("unexpected search results: %s", body)
+		}
+	})
+
+	t.Run("BulkDisable", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		c := s.NewClient(t)
+		c.MustLoginAdmin()
+		resp, _ := c.PostForm("/admin/links", url.Values{"action": {suss.ShortURLBulkDisable}, "id": {strconv.Itoa(shortUrl.ID)}, "next": {"/admin/"}})
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), "/admin/"; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+		if resp, _ := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusGone {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrNotAdmin", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		c.MustRegister("Susy", "susy@example.com", "password123")
+		if resp, _ := c.Get("/admin/"); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_AdminAuditExport(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		slug := s.NewClient(t).MustShorten(url.Values{"url": {"https://example.com/"}})
+
+		c := s.NewClient(t)
+		c.MustLoginAdmin()
+		resp, body := c.Get("/admin/audit/export?action=" + url.QueryEscape(suss.AuditShortURLCreate))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+			t.Fatalf("Content-Disposition=%q", got)
+		}
+
+		// changes made over http record the request and the visitor
+		var export AuditExportResponse
+		MustUnmarshal(t, body, &export)
+		if export.N != 1 || len(export.AuditEvents) != 1 {
+			t.Fatalf("n=%d, len=%d", export.N, len(export.AuditEvents))
+		} else if e := export.AuditEvents[0]; e.Slug != slug || e.ActorType != suss.AuditActorAnonymous {
+			t.Fatalf("Slug=%q, ActorType=%q", e.Slug, e.ActorType)
+		} else if e.RequestID == "" || e.ActorIPHash == "" {
+			t.Fatalf("RequestID=%q, ActorIPHash=%q", e.RequestID, e.ActorIPHash)
+		}
+
+		if resp, body := c.Get("/admin/audit?slug=" + slug); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, suss.AuditShortURLCreate) {
+			t.Fatalf("event not listed: %s", body)
+		}
+	})
+
+	t.Run("ErrFilter", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) { s.AdminToken = testAdminToken })
+		c := s.NewClient(t)
+		c.MustLoginAdmin()
+		if resp, _ := c.Get("/admin/audit/export?user_id=susy"); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
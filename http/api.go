@@ -0,0 +1,407 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httprate"
+	"github.com/heyjorgedev/suss"
+)
+
+// Page sizes used by list endpoints when no limit, or too large a limit, is given.
+const (
+	APIDefaultLimit = 20
+	APIMaxLimit     = 100
+)
+
+// APIMaxBodySize is the maximum size of a JSON request body, in bytes.
+const APIMaxBodySize = 8 << 10
+
+// APIShortURL is the representation of a short url returned by the API.
+// Fields follow the JSON names of suss.ShortURL and are only ever added to.
+type APIShortURL struct {
+	ID       int    `json:"id"`
+	Slug     string `json:"slug"`
+	ShortURL string `json:"short_url"`
+
+	// Destination of the short url. Omitted for password protected links.
+	LongURL string `json:"long_url,omitempty"`
+
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	MaxClicks         int        `json:"max_clicks"`
+	PasswordProtected bool       `json:"password_protected"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// APIShortURLCreateRequest is the body accepted by POST /api/v1/short-urls.
+type APIShortURLCreateRequest struct {
+	LongURL   string     `json:"long_url"`
+	Slug      string     `json:"slug"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxClicks int        `json:"max_clicks"`
+	Password  string     `json:"password"`
+	Email     string     `json:"email"`
+
+	// Workspace to create the short url in. Requires an API key of a user
+	// with the editor role in the workspace.
+	WorkspaceID int `json:"workspace_id"`
+
+	// Proof-of-work challenge from GET /api/v1/challenge and its solution.
+	// Only required without an API key, when challenges are enabled.
+	Challenge         string `json:"challenge,omitempty"`
+	ChallengeSolution string `json:"challenge_solution,omitempty"`
+}
+
+// APIChallengeResponse is returned by GET /api/v1/challenge. The challenge is
+// solved by finding a solution for which the SHA-256 hash of the challenge, a
+// colon and the solution starts with at least Difficulty zero bits.
+type APIChallengeResponse struct {
+	Challenge  string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// APIShortURLCreateResponse is returned after creating a short url. It is the
+// only response which includes the secret key used to manage the link.
+type APIShortURLCreateResponse struct {
+	APIShortURL
+	SecretKey string `json:"secret_key"`
+}
+
+// APIShortURLListResponse is returned by GET /api/v1/short-urls. N is the total
+// number of matching short urls, regardless of offset and limit.
+type APIShortURLListResponse struct {
+	ShortURLs []*APIShortURL `json:"short_urls"`
+	N         int            `json:"n"`
+	Offset    int            `json:"offset"`
+	Limit     int            `json:"limit"`
+}
+
+// APIError is the body of every non-2xx API response.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *Server) registerAPIRoutes(r chi.Router) {
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Get("/short-urls", s.handlerAPIShortUrlList())
+		r.Post("/short-urls", s.handlerAPIShortUrlCreate())
+		r.Get("/short-urls/{slug}", s.handlerAPIShortUrlGet())
+		r.Delete("/short-urls/{slug}", s.handlerAPIShortUrlDelete())
+		r.Get("/short-urls/{slug}/stats", s.handlerAPIShortUrlStats())
+		r.Get("/challenge", s.handlerAPIChallenge())
+		r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Endpoint not found."))
+		})
+	})
+}
+
+func (s *Server) handlerAPIShortUrlList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := suss.APIKeyFromContext(r.Context())
+		if key == nil {
+			s.Error(w, r, suss.Errorf(suss.EUNAUTHORIZED, "API key required."))
+			return
+		} else if err := requireAPIScope(r, suss.APIKeyScopeLinksRead); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		filter, err := parseAPIShortUrlFilter(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// keys list the personal links of their user, or the links of a
+		// workspace their user is a member of, even if they are an administrator
+		if filter.WorkspaceID == nil || *filter.WorkspaceID == 0 {
+			noWorkspace := 0
+			filter.OwnerID, filter.WorkspaceID = &key.UserID, &noWorkspace
+		}
+
+		shortUrls, n, err := s.ShortURLService.FindShortUrls(r.Context(), filter)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		resp := APIShortURLListResponse{
+			ShortURLs: make([]*APIShortURL, len(shortUrls)),
+			N:         n,
+			Offset:    filter.Offset,
+			Limit:     filter.Limit,
+		}
+		for i := range shortUrls {
+			resp.ShortURLs[i] = s.newAPIShortURL(r, shortUrls[i])
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Server) handlerAPIShortUrlCreate() http.HandlerFunc {
+	create := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := requireAPIScope(r, suss.APIKeyScopeLinksWrite); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		var req APIShortURLCreateRequest
+		var maxBytesErr *http.MaxBytesError
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, APIMaxBodySize)).Decode(&req); errors.As(err, &maxBytesErr) {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Request body must be at most %d bytes.", APIMaxBodySize))
+			return
+		} else if err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Invalid JSON body."))
+			return
+		} else if req.LongURL == "" {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Long url required."))
+			return
+		}
+
+		if err := s.requireChallenge(r, req.Challenge, req.ChallengeSolution); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		shortUrl := &suss.ShortURL{
+			Slug:        req.Slug,
+			LongURL:     req.LongURL,
+			MaxClicks:   req.MaxClicks,
+			Password:    req.Password,
+			Email:       req.Email,
+			WorkspaceID: req.WorkspaceID,
+		}
+		if req.ExpiresAt != nil {
+			shortUrl.ExpiresAt = req.ExpiresAt.UTC()
+		}
+		if err := s.ShortURLService.Create(r.Context(), shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+		s.recordAnonymousCreation(r)
+
+		writeJSON(w, http.StatusCreated, APIShortURLCreateResponse{
+			APIShortURL: *s.newAPIShortURL(r, shortUrl),
+			SecretKey:   shortUrl.SecretKey,
+		})
+	})
+
+	// anonymous clients are limited by ip, requests made with an API key
+	// only count towards the limit of their key
+	rateLimiter := httprate.NewRateLimiter(5, time.Minute, httprate.WithKeyByIP())
+	handler := rateLimiter.Handler(create)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if suss.APIKeyFromContext(r.Context()) != nil {
+			create.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+}
+
+func (s *Server) handlerAPIChallenge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.ChallengeEnabled() {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Challenges are not enabled."))
+			return
+		}
+
+		challenge, difficulty, expiresAt, err := s.newChallenge()
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, APIChallengeResponse{
+			Challenge:  challenge,
+			Difficulty: difficulty,
+			ExpiresAt:  expiresAt.UTC(),
+		})
+	}
+}
+
+func (s *Server) handlerAPIShortUrlGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := requireAPIScope(r, suss.APIKeyScopeLinksRead); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		shortUrl, err := s.findAPIManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, s.newAPIShortURL(r, shortUrl))
+	}
+}
+
+func (s *Server) handlerAPIShortUrlDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := requireAPIScope(r, suss.APIKeyScopeLinksWrite); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		shortUrl, err := s.findAPIManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if err := s.ShortURLService.Delete(r.Context(), shortUrl.ID); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handlerAPIShortUrlStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := requireAPIScope(r, suss.APIKeyScopeAnalyticsRead); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		shortUrl, err := s.findAPIManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		stats, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// findAPIManagedShortUrl looks up the short url for the "slug" route parameter
+// using the secret in the X-Secret-Key header. Without a secret, the short
+// url must belong to the authenticated user or one of their workspaces.
+func (s *Server) findAPIManagedShortUrl(r *http.Request) (*suss.ShortURL, error) {
+	slug := chi.URLParam(r, "slug")
+	if secret := r.Header.Get("X-Secret-Key"); secret != "" {
+		return s.ShortURLService.FindShortUrlBySecretKey(r.Context(), slug, secret)
+	}
+	return s.findUserShortUrl(r, slug)
+}
+
+// requireAPIScope returns an error if the request was authenticated with an
+// API key which was not granted scope. Other requests are checked by each
+// endpoint as before.
+func requireAPIScope(r *http.Request, scope string) error {
+	if key := suss.APIKeyFromContext(r.Context()); key != nil && !key.HasScope(scope) {
+		return suss.Errorf(suss.EUNAUTHORIZED, "API key is missing the %s scope.", scope)
+	}
+	return nil
+}
+
+// parseAPIShortUrlFilter builds a filter from the query string of a list request.
+func parseAPIShortUrlFilter(r *http.Request) (suss.ShortURLFilter, error) {
+	q := r.URL.Query()
+	filter := suss.ShortURLFilter{
+		Sort:  q.Get("sort"),
+		Limit: APIDefaultLimit,
+	}
+
+	if v := q.Get("id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, suss.Errorf(suss.EINVALID, "Invalid id.")
+		}
+		filter.ID = &id
+	}
+	if v := q.Get("slug"); v != "" {
+		filter.Slug = &v
+	}
+	if v := q.Get("workspace_id"); v != "" {
+		workspaceID, err := strconv.Atoi(v)
+		if err != nil || workspaceID < 0 {
+			return filter, suss.Errorf(suss.EINVALID, "Invalid workspace_id.")
+		}
+		filter.WorkspaceID = &workspaceID
+	}
+	if v := q.Get("long_url"); v != "" {
+		filter.LongURL = &v
+	}
+	if v := q.Get("host"); v != "" {
+		filter.Host = &v
+	}
+	if v := q.Get("created_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, suss.Errorf(suss.EINVALID, "Invalid created_after, expected an RFC 3339 date.")
+		}
+		filter.CreatedAfter = &t
+	}
+	if v := q.Get("created_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, suss.Errorf(suss.EINVALID, "Invalid created_before, expected an RFC 3339 date.")
+		}
+		filter.CreatedBefore = &t
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, suss.Errorf(suss.EINVALID, "Invalid offset.")
+		}
+		filter.Offset = offset
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > APIMaxLimit {
+			return filter, suss.Errorf(suss.EINVALID, "Limit must be between 1 and %d.", APIMaxLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// newAPIShortURL converts a short url into its API representation.
+func (s *Server) newAPIShortURL(r *http.Request, shortUrl *suss.ShortURL) *APIShortURL {
+	v := &APIShortURL{
+		ID:                shortUrl.ID,
+		Slug:              shortUrl.Slug,
+		ShortURL:          shortUrl.ShortURL(s.PublicURL(r)),
+		MaxClicks:         shortUrl.MaxClicks,
+		PasswordProtected: shortUrl.HasPassword(),
+		CreatedAt:         shortUrl.CreatedAt,
+		UpdatedAt:         shortUrl.UpdatedAt,
+	}
+	if !shortUrl.HasPassword() {
+		v.LongURL = shortUrl.LongURL
+	}
+	if !shortUrl.ExpiresAt.IsZero() {
+		expiresAt := shortUrl.ExpiresAt
+		v.ExpiresAt = &expiresAt
+	}
+	return v
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("cannot encode json response: %s", err)
+	}
+}
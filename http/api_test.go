@@ -0,0 +1,236 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// PostJSON posts body as JSON to path and returns the response and its body.
+func (c *Client) PostJSON(path, body string) (*http.Response, string) {
+	c.tb.Helper()
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.Do(req)
+}
+
+// MustCreateAPIKey creates a user with an API key granted scopes and returns
+// the user and the key.
+func (s *TestServer) MustCreateAPIKey(tb testing.TB, email string, scopes ...string) (*suss.User, string) {
+	tb.Helper()
+	user := &suss.User{Name: "Susy", Email: email, Password: "password123"}
+	if err := s.UserService.Create(context.Background(), user); err != nil {
+		tb.Fatal(err)
+	}
+	key := &suss.APIKey{UserID: user.ID, Name: "test", Scopes: scopes}
+	if err := s.APIKeyService.Create(suss.NewContextWithUser(context.Background(), user), key); err != nil {
+		tb.Fatal(err)
+	}
+	return user, key.Key
+}
+
+// DoAPI sends an API request authenticated with key, if any, and extra headers
+// given as name and value pairs.
+func (c *Client) DoAPI(method, path, key, body string, headers ...string) (*http.Response, string) {
+	c.tb.Helper()
+	req, err := http.NewRequest(method, c.baseURL+path, strings.NewReader(body))
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return c.Do(req)
+}
+
+// MustUnmarshal decodes the JSON body of a response into v.
+func MustUnmarshal(tb testing.TB, body string, v any) {
+	tb.Helper()
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		tb.Fatalf("%s: %s", err, body)
+	}
+}
+
+func TestServer_APIShortUrlCreate(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+
+		resp, body := c.PostJSON("/api/v1/short-urls", `{"long_url":"https://example.com/","slug":"api-link"}`)
+		var created APIShortURLCreateResponse
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		MustUnmarshal(t, body, &created)
+		if created.Slug != "api-link" || created.LongURL != "https://example.com/" {
+			t.Fatalf("unexpected short url: %#v", created)
+		} else if created.SecretKey == "" {
+			t.Fatal("expected secret key")
+		} else if got, want := created.ShortURL, s.URL()+"/api-link"; got != want {
+			t.Fatalf("ShortURL=%q, want %q", got, want)
+		}
+
+		// the secret key manages the link without an API key
+		resp, body = c.DoAPI(http.MethodGet, "/api/v1/short-urls/api-link", "", "", "X-Secret-Key", created.SecretKey)
+		var shortUrl APIShortURL
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		MustUnmarshal(t, body, &shortUrl)
+		if shortUrl.ID != created.ID {
+			t.Fatalf("ID=%d, want %d", shortUrl.ID, created.ID)
+		}
+	})
+
+	t.Run("PasswordProtected", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+
+		_, body := c.PostJSON("/api/v1/short-urls", `{"long_url":"https://example.com/","password":"hunter22"}`)
+		var created APIShortURLCreateResponse
+		MustUnmarshal(t, body, &created)
+		if !created.PasswordProtected {
+			t.Fatal("expected password protected")
+		} else if created.LongURL != "" {
+			t.Fatalf("LongURL=%q, want it omitted", created.LongURL)
+		}
+	})
+
+	t.Run("ErrLongURLRequired", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		if resp, _ := s.NewClient(t).PostJSON("/api/v1/short-urls", `{"slug":"api-link"}`); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrScope", func(t *testing.T) {
+		// forbidden rather than unauthorized, as the key's user is signed in
+		s := MustOpenServer(t, nil)
+		_, key := s.MustCreateAPIKey(t, "susy@example.com", suss.APIKeyScopeLinksRead)
+		if resp, _ := s.NewClient(t).DoAPI(http.MethodPost, "/api/v1/short-urls", key, `{"long_url":"https://example.com/"}`); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrBodyTooLarge", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		body := `{"long_url":"https://example.com/","slug":"` + strings.Repeat("a", APIMaxBodySize) + `"}`
+
+		resp, respBody := s.NewClient(t).PostJSON("/api/v1/short-urls", body)
+		var apiErr APIError
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if err := json.Unmarshal([]byte(respBody), &apiErr); err != nil {
+			t.Fatal(err)
+		} else if apiErr.Code != suss.EINVALID || !strings.Contains(apiErr.Message, "at most") {
+			t.Fatalf("unexpected error: %#v", apiErr)
+		}
+	})
+}
+
+func TestServer_APIShortUrlList(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		_, key0 := s.MustCreateAPIKey(t, "susy@example.com", suss.APIKeyScopes...)
+		_, key1 := s.MustCreateAPIKey(t, "john@example.com", suss.APIKeyScopes...)
+		for _, body := range []string{`{"long_url":"https://example.com/0"}`, `{"long_url":"https://example.com/1"}`} {
+			if resp, _ := c.DoAPI(http.MethodPost, "/api/v1/short-urls", key0, body); resp.StatusCode != http.StatusCreated {
+				t.Fatalf("StatusCode=%d", resp.StatusCode)
+			}
+		}
+		if resp, _ := c.DoAPI(http.MethodPost, "/api/v1/short-urls", key1, `{"long_url":"https://example.com/john"}`); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// keys only list the links of their own user
+		resp, body := c.DoAPI(http.MethodGet, "/api/v1/short-urls?limit=1", key0, "")
+		var list APIShortURLListResponse
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		MustUnmarshal(t, body, &list)
+		if list.N != 2 || len(list.ShortURLs) != 1 || list.Limit != 1 {
+			t.Fatalf("n=%d, len=%d, limit=%d", list.N, len(list.ShortURLs), list.Limit)
+		}
+	})
+
+	t.Run("ErrAPIKeyRequired", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		resp, body := s.NewClient(t).DoAPI(http.MethodGet, "/api/v1/short-urls", "", "")
+		var apiErr APIError
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		MustUnmarshal(t, body, &apiErr)
+		if apiErr.Code != suss.EUNAUTHORIZED {
+			t.Fatalf("Code=%q", apiErr.Code)
+		}
+	})
+
+	t.Run("ErrInvalidLimit", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		_, key := s.MustCreateAPIKey(t, "susy@example.com", suss.APIKeyScopes...)
+		if resp, _ := s.NewClient(t).DoAPI(http.MethodGet, "/api/v1/short-urls?limit=1000", key, ""); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_APIShortUrlDelete(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		_, body := c.PostJSON("/api/v1/short-urls", `{"long_url":"https://example.com/","slug":"api-link"}`)
+		var created APIShortURLCreateResponse
+		MustUnmarshal(t, body, &created)
+
+		if resp, _ := c.DoAPI(http.MethodDelete, "/api/v1/short-urls/api-link", "", "", "X-Secret-Key", created.SecretKey); resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if _, err := s.ShortURLService.FindDialBySlug(context.Background(), "api-link"); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrSecretKey", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		c.PostJSON("/api/v1/short-urls", `{"long_url":"https://example.com/","slug":"api-link"}`)
+
+		if resp, _ := c.DoAPI(http.MethodDelete, "/api/v1/short-urls/api-link", "", "", "X-Secret-Key", "bogus"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if _, err := s.ShortURLService.FindDialBySlug(context.Background(), "api-link"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestServer_APINotFound(t *testing.T) {
+	s := MustOpenServer(t, nil)
+	resp, body := s.NewClient(t).DoAPI(http.MethodGet, "/api/v1/nowhere", "", "")
+	var apiErr APIError
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("StatusCode=%d", resp.StatusCode)
+	} else if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Fatalf("Content-Type=%q", got)
+	}
+	MustUnmarshal(t, body, &apiErr)
+	if apiErr.Code != suss.ENOTFOUND {
+		t.Fatalf("Code=%q", apiErr.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+func (s *Server) handlerAPIKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.renderAPIKeysPage(w, r, html.APIKeysPageProps{
+			Scopes: []string{suss.APIKeyScopeLinksRead},
+		})
+	}
+}
+
+func (s *Server) handlerAPIKeyCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Invalid form."))
+			return
+		}
+
+		key := &suss.APIKey{
+			Name:   strings.TrimSpace(r.PostFormValue("name")),
+			Scopes: r.PostForm["scope"],
+		}
+		if err := s.APIKeyService.Create(r.Context(), key); suss.ErrorCode(err) == suss.EINVALID {
+			w.WriteHeader(http.StatusBadRequest)
+			s.renderAPIKeysPage(w, r, html.APIKeysPageProps{
+				Name:   key.Name,
+				Scopes: key.Scopes,
+				Error:  suss.ErrorMessage(err),
+			})
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// render instead of redirecting so the key is shown once and never
+		// ends up in a url
+		s.renderAPIKeysPage(w, r, html.APIKeysPageProps{
+			NewKey: key,
+			Scopes: []string{suss.APIKeyScopeLinksRead},
+		})
+	}
+}
+
+func (s *Server) handlerAPIKeyDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "API key not found."))
+			return
+		}
+
+		if err := s.APIKeyService.Delete(r.Context(), id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/api-keys", http.StatusSeeOther)
+	}
+}
+
+// renderAPIKeysPage renders the keys of the signed in user along with props.
+func (s *Server) renderAPIKeysPage(w http.ResponseWriter, r *http.Request, props html.APIKeysPageProps) {
+	if suss.UserFromContext(r.Context()) == nil {
+		http.Redirect(w, r, "/login?next=/api-keys", http.StatusSeeOther)
+		return
+	}
+
+	keys, _, err := s.APIKeyService.FindAPIKeys(r.Context(), suss.APIKeyFilter{})
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	props.APIKeys = keys
+	html.APIKeysPage(props).Render(r.Context(), w)
+}
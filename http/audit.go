@@ -0,0 +1,121 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// AuditEventsPageSize is the number of events listed per page on /admin/audit.
+const AuditEventsPageSize = 50
+
+// AuditExportResponse is returned by the audit log export.
+type AuditExportResponse struct {
+	AuditEvents []*suss.AuditEvent `json:"audit_events"`
+	N           int                `json:"n"`
+}
+
+func (s *Server) handlerAdminAuditEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		props, filter, err := parseAuditEventFilter(r.URL.Query())
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		props.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+		if props.Offset < 0 {
+			props.Offset = 0
+		}
+		props.Limit = AuditEventsPageSize
+		filter.Offset, filter.Limit = props.Offset, props.Limit
+
+		if props.Events, props.N, err = s.AuditService.FindAuditEvents(r.Context(), filter); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		html.AdminAuditEventsPage(props).Render(r.Context(), w)
+	}
+}
+
+// handlerAdminAuditExport returns every event matching the filter as JSON.
+func (s *Server) handlerAdminAuditExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, filter, err := parseAuditEventFilter(r.URL.Query())
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		events, n, err := s.AuditService.FindAuditEvents(r.Context(), filter)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.json"`, time.Now().UTC().Format("20060102-150405")))
+		writeJSON(w, http.StatusOK, &AuditExportResponse{
+			AuditEvents: events,
+			N:           n,
+		})
+	}
+}
+
+// parseAuditEventFilter reads the audit log filter from query parameters.
+// Dates are whole days in UTC, both inclusive.
+func parseAuditEventFilter(q url.Values) (html.AdminAuditEventsPageProps, suss.AuditEventFilter, error) {
+	props := html.AdminAuditEventsPageProps{
+		Action:    q.Get("action"),
+		ActorType: q.Get("actor_type"),
+		UserID:    strings.TrimSpace(q.Get("user_id")),
+		Slug:      strings.TrimSpace(q.Get("slug")),
+		RequestID: strings.TrimSpace(q.Get("request_id")),
+		From:      q.Get("from"),
+		To:        q.Get("to"),
+	}
+
+	var filter suss.AuditEventFilter
+	if props.Action != "" {
+		filter.Action = &props.Action
+	}
+	if props.ActorType != "" {
+		filter.ActorType = &props.ActorType
+	}
+	if props.UserID != "" {
+		userID, err := strconv.Atoi(props.UserID)
+		if err != nil {
+			return props, filter, suss.Errorf(suss.EINVALID, "Invalid user ID %q.", props.UserID)
+		}
+		filter.ActorUserID = &userID
+	}
+	if props.Slug != "" {
+		filter.Slug = &props.Slug
+	}
+	if props.RequestID != "" {
+		filter.RequestID = &props.RequestID
+	}
+	if props.From != "" {
+		from, err := time.Parse(time.DateOnly, props.From)
+		if err != nil {
+			return props, filter, suss.Errorf(suss.EINVALID, "Invalid date %q.", props.From)
+		}
+		filter.CreatedAfter = &from
+	}
+	if props.To != "" {
+		to, err := time.Parse(time.DateOnly, props.To)
+		if err != nil {
+			return props, filter, suss.Errorf(suss.EINVALID, "Invalid date %q.", props.To)
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.CreatedBefore = &to
+	}
+
+	return props, filter, nil
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// ChallengeTTL is how long a proof-of-work challenge can be solved for.
+const ChallengeTTL = 10 * time.Minute
+
+// MaxChallengeDifficulty caps the difficulty, in leading zero bits, however
+// much creations spike. Browsers take a few seconds at this level.
+const MaxChallengeDifficulty = 22
+
+// challenger tracks the proof-of-work challenges solved, so each is only used
+// once, and the rate of anonymous link creation used to raise the difficulty.
+type challenger struct {
+	mu sync.Mutex
+
+	// solved challenges and when they expire
+	solved map[string]time.Time
+
+	// creations counted in the current and previous minute
+	window   time.Time
+	current  int
+	previous int
+}
+
+func newChallenger() *challenger {
+	return &challenger{
+		solved: make(map[string]time.Time),
+	}
+}
+
+// recordCreation counts an anonymous link creation at now.
+func (c *challenger) recordCreation(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slide(now)
+	c.current++
+}
+
+// rate returns the number of creations over the last minute, weighing the
+// previous minute by how much of it is still within the last minute.
+func (c *challenger) rate(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slide(now)
+	elapsed := now.Sub(c.window)
+	return c.current + int(int64(c.previous)*int64(time.Minute-elapsed)/int64(time.Minute))
+}
+
+// slide moves the counting window to the minute of now. Callers hold the lock.
+func (c *challenger) slide(now time.Time) {
+	window := now.Truncate(time.Minute)
+	if window.Equal(c.window) {
+		return
+	} else if window.Sub(c.window) == time.Minute {
+		c.previous = c.current
+	} else {
+		c.previous = 0
+	}
+	c.window, c.current = window, 0
+}
+
+// markSolved records challenge as used until it expires. Returns false if it
+// was already used.
+func (c *challenger) markSolved(challenge string, expiresAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.solved[challenge]; ok {
+		return false
+	}
+
+	// forget challenges which can no longer be replayed anyway
+	for k, v := range c.solved {
+		if !now.Before(v) {
+			delete(c.solved, k)
+		}
+	}
+
+	c.solved[challenge] = expiresAt
+	return true
+}
+
+// ChallengeEnabled returns true if anonymous visitors must solve a challenge
+// to create links.
+func (s *Server) ChallengeEnabled() bool {
+	return s.ChallengeDifficulty > 0
+}
+
+// challengeDifficulty returns the number of leading zero bits currently
+// required. Each time creations double past the spike threshold, the
+// difficulty rises by one bit, doubling the work of every new link.
+func (s *Server) challengeDifficulty() int {
+	difficulty := s.ChallengeDifficulty
+	if s.ChallengeSpikeThreshold > 0 {
+		difficulty += bits.Len(uint(s.challenger.rate(time.Now()) / s.ChallengeSpikeThreshold))
+	}
+	return min(difficulty, MaxChallengeDifficulty)
+}
+
+// newChallenge returns a signed challenge at the current difficulty. It holds
+// its expiry, difficulty and a random nonce, so nothing is stored until solved.
+func (s *Server) newChallenge() (challenge string, difficulty int, expiresAt time.Time, err error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", 0, time.Time{}, err
+	}
+
+	difficulty, expiresAt = s.challengeDifficulty(), time.Now().Add(ChallengeTTL).Truncate(time.Second)
+	v := fmt.Sprintf("%d.%d.%s", expiresAt.Unix(), difficulty, hex.EncodeToString(nonce))
+	return v + "." + s.hash("challenge:"+v), difficulty, expiresAt, nil
+}
+
+// requireChallenge returns an error unless the request is authenticated or
+// solves a challenge issued by newChallenge at the current difficulty.
+func (s *Server) requireChallenge(r *http.Request, challenge, solution string) error {
+	if !s.ChallengeEnabled() || suss.UserFromContext(r.Context()) != nil {
+		return nil
+	} else if challenge == "" || solution == "" {
+		return suss.Errorf(suss.EINVALID, "Challenge solution required.")
+	}
+
+	// verify the challenge was issued by us and has not expired
+	parts := strings.SplitN(challenge, ".", 4)
+	if len(parts) != 4 {
+		return suss.Errorf(suss.EINVALID, "Invalid challenge.")
+	}
+	v, mac := strings.Join(parts[:3], "."), parts[3]
+	if subtle.ConstantTimeCompare([]byte(mac), []byte(s.hash("challenge:"+v))) != 1 {
+		return suss.Errorf(suss.EINVALID, "Invalid challenge.")
+	}
+
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return suss.Errorf(suss.EINVALID, "Invalid challenge.")
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return suss.Errorf(suss.EINVALID, "Invalid challenge.")
+	}
+
+	now := time.Now()
+	expiresAt := time.Unix(expires, 0)
+	if !now.Before(expiresAt) {
+		return suss.Errorf(suss.EINVALID, "The challenge expired, please reload the page and try again.")
+	} else if difficulty < s.challengeDifficulty() {
+		return suss.Errorf(suss.EINVALID, "The challenge got harder due to heavy traffic, please reload the page and try again.")
+	}
+
+	// the solution must give a hash with enough leading zero bits
+	if leadingZeroBits(sha256.Sum256([]byte(challenge+":"+solution))) < difficulty {
+		return suss.Errorf(suss.EINVALID, "Invalid challenge solution.")
+	}
+
+	if !s.challenger.markSolved(challenge, expiresAt, now) {
+		return suss.Errorf(suss.EINVALID, "This challenge was already used, please reload the page and try again.")
+	}
+	return nil
+}
+
+// recordAnonymousCreation counts a link created without signing in towards
+// the creation rate which raises the challenge difficulty.
+func (s *Server) recordAnonymousCreation(r *http.Request) {
+	if suss.UserFromContext(r.Context()) == nil {
+		s.challenger.recordCreation(time.Now())
+	}
+}
+
+// leadingZeroBits returns the number of leading zero bits of sum.
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// solveChallenge returns a solution of challenge at difficulty.
+func solveChallenge(challenge string, difficulty int) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if leadingZeroBits(sha256.Sum256([]byte(challenge+":"+solution))) >= difficulty {
+			return solution
+		}
+	}
+}
+
+// MustSolveChallenge requests a challenge from the API and solves it.
+func (c *Client) MustSolveChallenge() (challenge, solution string) {
+	c.tb.Helper()
+	resp, body := c.Get("/api/v1/challenge")
+	if resp.StatusCode != http.StatusOK {
+		c.tb.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+	}
+	var v APIChallengeResponse
+	MustUnmarshal(c.tb, body, &v)
+	return v.Challenge, solveChallenge(v.Challenge, v.Difficulty)
+}
+
+// challengeBody returns a create request body with a challenge and solution.
+func challengeBody(challenge, solution string) string {
+	buf, _ := json.Marshal(APIShortURLCreateRequest{
+		LongURL:           "https://example.com/",
+		Challenge:         challenge,
+		ChallengeSolution: solution,
+	})
+	return string(buf)
+}
+
+func TestServer_Challenge(t *testing.T) {
+	configure := func(s *TestServer) { s.ChallengeDifficulty = 8 }
+
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, configure)
+		c := s.NewClient(t)
+		challenge, solution := c.MustSolveChallenge()
+		if resp, body := c.PostJSON("/api/v1/short-urls", challengeBody(challenge, solution)); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("ErrReplay", func(t *testing.T) {
+		s := MustOpenServer(t, configure)
+		c := s.NewClient(t)
+		challenge, solution := c.MustSolveChallenge()
+		if resp, _ := c.PostJSON("/api/v1/short-urls", challengeBody(challenge, solution)); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// a solved challenge only creates one link
+		if resp, body := c.PostJSON("/api/v1/short-urls", challengeBody(challenge, solution)); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "already used") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		s := MustOpenServer(t, configure)
+		c := s.NewClient(t)
+		challenge, solution := c.MustSolveChallenge()
+
+		// find a solution which falls short of the difficulty
+		wrong := 0
+		for leadingZeroBits(sha256.Sum256([]byte(challenge+":"+strconv.Itoa(wrong)))) >= 8 {
+			wrong++
+		}
+		parts := strings.Split(challenge, ".")
+		parts[1] = "1" // easier difficulty, invalidating the signature
+		for _, body := range []string{
+			challengeBody("", ""),
+			challengeBody(challenge, strconv.Itoa(wrong)),
+			challengeBody(strings.Join(parts, "."), solution),
+			challengeBody("bogus", solution),
+		} {
+			if resp, _ := c.PostJSON("/api/v1/short-urls", body); resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("%s: StatusCode=%d", body, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("ErrExpired", func(t *testing.T) {
+		s := MustOpenServer(t, configure)
+
+		// sign a challenge which expired a second ago
+		v := fmt.Sprintf("%d.%d.%s", time.Now().Add(-time.Second).Unix(), 8, "00112233445566778899aabbccddeeff")
+		challenge := v + "." + s.hash("challenge:"+v)
+		resp, body := s.NewClient(t).PostJSON("/api/v1/short-urls", challengeBody(challenge, solveChallenge(challenge, 8)))
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "expired") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		// signed in callers are never challenged
+		s := MustOpenServer(t, configure)
+		_, key := s.MustCreateAPIKey(t, "susy@example.com", suss.APIKeyScopes...)
+		if resp, body := s.NewClient(t).DoAPI(http.MethodPost, "/api/v1/short-urls", key, `{"long_url":"https://example.com/"}`); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		if resp, _ := s.NewClient(t).Get("/api/v1/challenge"); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_ChallengeDifficulty(t *testing.T) {
+	s := MustOpenServer(t, func(s *TestServer) {
+		s.ChallengeDifficulty = 8
+		s.ChallengeSpikeThreshold = 2
+	})
+	c := s.NewClient(t)
+	challenge, solution := c.MustSolveChallenge()
+
+	// each doubling of creations past the threshold adds a bit
+	for want, n := range map[int]int{8: 1, 9: 2, 10: 4} {
+		s.challenger = newChallenger()
+		for i := 0; i < n; i++ {
+			s.challenger.recordCreation(time.Now())
+		}
+		if got := s.challengeDifficulty(); got != want {
+			t.Fatalf("%d creations: difficulty=%d, want %d", n, got, want)
+		}
+	}
+
+	// challenges issued before the spike must be requested again
+	resp, body := c.PostJSON("/api/v1/short-urls", challengeBody(challenge, solution))
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("StatusCode=%d", resp.StatusCode)
+	} else if !strings.Contains(body, "harder") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
+
+func TestChallenger_Rate(t *testing.T) {
+	c := newChallenger()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		c.recordCreation(now)
+	}
+
+	// the previous minute is weighed by how much of it is still recent
+	if got := c.rate(now.Add(90 * time.Second)); got != 5 {
+		t.Fatalf("rate=%d, want 5", got)
+	} else if got := c.rate(now.Add(3 * time.Minute)); got != 0 {
+		t.Fatalf("rate=%d, want 0", got)
+	}
+}
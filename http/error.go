@@ -1,21 +1,99 @@
 package http
 
 import (
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/heyjorgedev/suss"
 	"github.com/heyjorgedev/suss/http/html"
 )
 
+// lookup of application error codes to HTTP status codes.
+var codes = map[string]int{
+	suss.ECONFLICT:       http.StatusConflict,
+	suss.EINVALID:        http.StatusBadRequest,
+	suss.ENOTFOUND:       http.StatusNotFound,
+	suss.ENOTIMPLEMENTED: http.StatusNotImplemented,
+	suss.EUNAUTHORIZED:   http.StatusUnauthorized,
+	suss.EINTERNAL:       http.StatusInternalServerError,
+}
+
+// ErrorStatusCode returns the associated HTTP status code for a suss error code.
+func ErrorStatusCode(code string) int {
+	if v, ok := codes[code]; ok {
+		return v
+	}
+	return http.StatusInternalServerError
+}
+
+// requestStatusCode returns the HTTP status code for code in response to r.
+// Signed in callers lacking permission are forbidden rather than unauthorized,
+// as signing in again would not help.
+func requestStatusCode(r *http.Request, code string) int {
+	if code == suss.EUNAUTHORIZED && suss.UserFromContext(r.Context()) != nil {
+		return http.StatusForbidden
+	}
+	return ErrorStatusCode(code)
+}
+
+// Error writes err to the response as JSON for API clients or as an HTML page
+// for browsers. Internal errors are logged and only a generic message is shown.
 func (s *Server) Error(w http.ResponseWriter, r *http.Request, err error) {
 	if err == nil {
 		return
 	}
 
-	if suss.ErrorIsNotFound(err) {
+	code, message := suss.ErrorCode(err), suss.ErrorMessage(err)
+	if code == suss.EINTERNAL {
+		log.Printf("http error: %s %s: %s", r.Method, r.URL.Path, err)
+	}
+	status := requestStatusCode(r, code)
+
+	if wantsJSON(r) {
+		writeJSON(w, status, APIError{
+			Code:    code,
+			Message: message,
+		})
+		return
+	}
+
+	w.WriteHeader(status)
+	if code == suss.ENOTFOUND {
 		html.NotFoundPage().Render(r.Context(), w)
 		return
 	}
+	html.ErrorPage(html.ErrorPageProps{
+		StatusCode: status,
+		Message:    message,
+	}).Render(r.Context(), w)
+}
+
+// wantsJSON returns true if the client expects a JSON response, either because
+// it is calling the API or because it explicitly accepts JSON over HTML.
+func wantsJSON(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// csrfError responds to a form post with a missing or mismatched CSRF token.
+func (s *Server) csrfError(w http.ResponseWriter, r *http.Request) {
+	const message = "This form has expired or was sent from another site. Go back, reload the page and try again."
+
+	if wantsJSON(r) {
+		writeJSON(w, http.StatusForbidden, APIError{
+			Code:    suss.EUNAUTHORIZED,
+			Message: message,
+		})
+		return
+	}
 
-	http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.WriteHeader(http.StatusForbidden)
+	html.ErrorPage(html.ErrorPageProps{
+		StatusCode: http.StatusForbidden,
+		Message:    message,
+	}).Render(r.Context(), w)
 }
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestErrorStatusCode(t *testing.T) {
+	for code, want := range map[string]int{
+		suss.ECONFLICT:       http.StatusConflict,
+		suss.EINVALID:        http.StatusBadRequest,
+		suss.ENOTFOUND:       http.StatusNotFound,
+		suss.ENOTIMPLEMENTED: http.StatusNotImplemented,
+		suss.EUNAUTHORIZED:   http.StatusUnauthorized,
+		suss.EINTERNAL:       http.StatusInternalServerError,
+		"EUNKNOWN":           http.StatusInternalServerError,
+	} {
+		if got := ErrorStatusCode(code); got != want {
+			t.Fatalf("%s: status=%d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestServer_Error(t *testing.T) {
+	s := NewServer()
+
+	t.Run("JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.Error(w, httptest.NewRequest(http.MethodGet, "/api/v1/short-urls/abc", nil), suss.Errorf(suss.EINVALID, "Invalid secret."))
+
+		var apiErr APIError
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", w.Code)
+		}
+		MustUnmarshal(t, w.Body.String(), &apiErr)
+		if apiErr.Code != suss.EINVALID || apiErr.Message != "Invalid secret." {
+			t.Fatalf("unexpected error: %#v", apiErr)
+		}
+	})
+
+	t.Run("AcceptJSON", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		s.Error(w, r, suss.Errorf(suss.ECONFLICT, "Slug taken."))
+		if w.Code != http.StatusConflict {
+			t.Fatalf("StatusCode=%d", w.Code)
+		} else if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+			t.Fatalf("Content-Type=%q", got)
+		}
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/abc", nil)
+		r.Header.Set("Accept", "text/html,application/json")
+		w := httptest.NewRecorder()
+		s.Error(w, r, suss.Errorf(suss.EINVALID, "Invalid secret."))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", w.Code)
+		} else if body := w.Body.String(); !strings.Contains(body, "<html") || !strings.Contains(body, "Invalid secret.") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("Internal", func(t *testing.T) {
+		// internal messages are never shown to the client
+		w := httptest.NewRecorder()
+		s.Error(w, httptest.NewRequest(http.MethodGet, "/api/v1/short-urls", nil), errors.New("sql: database is locked"))
+
+		var apiErr APIError
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("StatusCode=%d", w.Code)
+		}
+		MustUnmarshal(t, w.Body.String(), &apiErr)
+		if apiErr.Code != suss.EINTERNAL || strings.Contains(apiErr.Message, "sql") {
+			t.Fatalf("unexpected error: %#v", apiErr)
+		}
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		// signed in users lacking permission are forbidden
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/short-urls", nil)
+		r = r.WithContext(suss.NewContextWithUser(context.Background(), &suss.User{ID: 1}))
+		w := httptest.NewRecorder()
+		s.Error(w, r, suss.Errorf(suss.EUNAUTHORIZED, "Not allowed."))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", w.Code)
+		}
+	})
+}
+
+func TestServer_NotFound(t *testing.T) {
+	s := MustOpenServer(t, nil)
+	resp, body := s.NewClient(t).Get("/no-such-slug")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("StatusCode=%d", resp.StatusCode)
+	} else if !strings.Contains(body, "<html") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+}
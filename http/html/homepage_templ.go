@@ -8,7 +8,33 @@ package html
 import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
-func Homepage() templ.Component {
+import (
+	"fmt"
+	"github.com/heyjorgedev/suss"
+	"strconv"
+)
+
+type HomepageProps struct {
+	RecentShortURLs []HomepageShortURL
+
+	// MailEnabled is true when an owner email can be used to recover links.
+	MailEnabled bool
+
+	// Proof-of-work challenge to solve before creating a link, empty when
+	// not required, and the number of leading zero bits its hash needs.
+	Challenge           string
+	ChallengeDifficulty int
+}
+
+// HomepageShortURL is a link recently created by the visitor.
+type HomepageShortURL struct {
+	Url       string
+	ManageURL string
+	ShortURL  *suss.ShortURL
+	Visits    int
+}
+
+func Homepage(props HomepageProps) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -87,39 +113,73 @@ func Homepage() templ.Component {
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, " <main class=\"max-w-7xl mx-auto px-6\"><div class=\"py-18 sm:py-24 lg:py-32 grid gap-4\"><h1 class=\"text-3xl sm:text-4xl lg:text-6xl font-medium tracking-tight lg:text-center\">Shorten URLs without the bloat.</h1><p class=\"lg:text-center\">A fast, simple, and privacy-friendly link shortener.</p></div><div class=\"max-w-4xl mx-auto\"><form method=\"post\" action=\"/shorten\"><div class=\"bg-zinc-200/80 dark:bg-zinc-800/60 rounded-xl p-2 flex flex-col sm:flex-row items-center gap-2 shadow-lg/2 ring-1 ring-zinc-100/80 dark:ring-zinc-800\"><input name=\"url\" type=\"url\" class=\"flex-1 min-h-16 bg-white dark:bg-zinc-700 w-full rounded-lg text-lg font-medium p-4 placeholder:text-zinc-500 ring-1 ring-zinc-200 dark:ring-zinc-600 focus:outline-zinc-400 dark:focus:outline-zinc-500 focus:outline-solid outline-none\" placeholder=\"Paste here your long url\"> <button class=\"sm:-mt-2 w-full sm:w-auto mb-2 sm:mb-0 cursor-pointer bg-blue-600 rounded-lg relative after:absolute after:inset-0 after:-bottom-2 after:bg-blue-700 after:rounded-lg after:-z-10 isolate after:ring after:ring-inset after:ring-blue-600/50 hover:translate-y-0.5 hover:after:-translate-y-0.5 hover:after:top-0.5\"><div class=\"bg-blue-600 py-4 px-6 rounded-lg text-white ring ring-inset ring-blue-500/80 font-semibold\">Make it short</div></button></div></form></div><div class=\"pt-16 sm:pt-24 lg:pt-32\"><h1 class=\"text-2xl sm:text-3xl font-medium tracking-tight lg:text-center\">Recently created by you</h1><div class=\"mt-12 md:mt-20 px-6 border rounded-xl border-zinc-200 dark:border-zinc-700 divide-y divide-zinc-300 dark:divide-zinc-700 bg-white dark:bg-zinc-900 shadow-lg/2\">")
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, " <main class=\"max-w-7xl mx-auto px-6\"><div class=\"py-18 sm:py-24 lg:py-32 grid gap-4\"><h1 class=\"text-3xl sm:text-4xl lg:text-6xl font-medium tracking-tight lg:text-center\">Shorten URLs without the bloat.</h1><p class=\"lg:text-center\">A fast, simple, and privacy-friendly link shortener.</p></div><div class=\"max-w-4xl mx-auto\"><form id=\"shorten\" method=\"post\" action=\"/shorten\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				templ_7745c5c3_Err = csrfField().Render(ctx, templ_7745c5c3_Buffer)
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				if props.Challenge != "" {
+					templ_7745c5c3_Err = homepageChallenge(props).Render(ctx, templ_7745c5c3_Buffer)
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<div class=\"bg-zinc-200/80 dark:bg-zinc-800/60 rounded-xl p-2 flex flex-col sm:flex-row items-center gap-2 shadow-lg/2 ring-1 ring-zinc-100/80 dark:ring-zinc-800\"><input name=\"url\" type=\"url\" class=\"flex-1 min-h-16 bg-white dark:bg-zinc-700 w-full rounded-lg text-lg font-medium p-4 placeholder:text-zinc-500 ring-1 ring-zinc-200 dark:ring-zinc-600 focus:outline-zinc-400 dark:focus:outline-zinc-500 focus:outline-solid outline-none\" placeholder=\"Paste here your long url\"> <button class=\"sm:-mt-2 w-full sm:w-auto mb-2 sm:mb-0 cursor-pointer bg-blue-600 rounded-lg relative after:absolute after:inset-0 after:-bottom-2 after:bg-blue-700 after:rounded-lg after:-z-10 isolate after:ring after:ring-inset after:ring-blue-600/50 hover:translate-y-0.5 hover:after:-translate-y-0.5 hover:after:top-0.5\"><div class=\"bg-blue-600 py-4 px-6 rounded-lg text-white ring ring-inset ring-blue-500/80 font-semibold\">Make it short</div></button></div><details class=\"mt-4 px-2 text-sm\"><summary class=\"cursor-pointer text-zinc-600 dark:text-zinc-400\">More options</summary><div class=\"mt-4 grid sm:grid-cols-2 gap-4\"><label class=\"grid gap-1 sm:col-span-2\"><span class=\"font-medium\">Custom slug</span> <input name=\"slug\" type=\"text\" minlength=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				var templ_7745c5c3_Var5 string
+				templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(suss.SlugMinLength))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 64, Col: 84}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "\" maxlength=\"")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				var templ_7745c5c3_Var6 string
+				templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(suss.SlugMaxLength))
+				if templ_7745c5c3_Err != nil {
+					return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 64, Col: 131}
+				}
+				_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "\" pattern=\"[A-Za-z0-9_\\-]+\" placeholder=\"Random\" class=\"bg-white dark:bg-zinc-700 rounded-lg p-3 ring-1 ring-zinc-200 dark:ring-zinc-600 outline-none\"></label> <label class=\"grid gap-1\"><span class=\"font-medium\">Expires on (UTC)</span> <input name=\"expires_at\" type=\"datetime-local\" class=\"bg-white dark:bg-zinc-700 rounded-lg p-3 ring-1 ring-zinc-200 dark:ring-zinc-600 outline-none\"></label> <label class=\"grid gap-1\"><span class=\"font-medium\">Maximum clicks</span> <input name=\"max_clicks\" type=\"number\" min=\"0\" placeholder=\"Unlimited\" class=\"bg-white dark:bg-zinc-700 rounded-lg p-3 ring-1 ring-zinc-200 dark:ring-zinc-600 outline-none\"></label> <label class=\"grid gap-1 sm:col-span-2\"><span class=\"font-medium\">Password</span> <input name=\"password\" type=\"password\" autocomplete=\"new-password\" maxlength=\"72\" placeholder=\"None\" class=\"bg-white dark:bg-zinc-700 rounded-lg p-3 ring-1 ring-zinc-200 dark:ring-zinc-600 outline-none\"></label> ")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = homepageShortUrlListItem().Render(ctx, templ_7745c5c3_Buffer)
+				if props.MailEnabled {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, "<label class=\"grid gap-1 sm:col-span-2\"><span class=\"font-medium\">Your email</span> <input name=\"email\" type=\"email\" autocomplete=\"email\" placeholder=\"Optional, to recover the manage link\" class=\"bg-white dark:bg-zinc-700 rounded-lg p-3 ring-1 ring-zinc-200 dark:ring-zinc-600 outline-none\"></label>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</div></details></form></div><div class=\"pt-16 sm:pt-24 lg:pt-32\"><h1 class=\"text-2xl sm:text-3xl font-medium tracking-tight lg:text-center\">Recently created by you</h1><div class=\"mt-12 md:mt-20 px-6 border rounded-xl border-zinc-200 dark:border-zinc-700 divide-y divide-zinc-300 dark:divide-zinc-700 bg-white dark:bg-zinc-900 shadow-lg/2\">")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
-				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "</div></div></main>")
+				for _, item := range props.RecentShortURLs {
+					templ_7745c5c3_Err = homepageShortUrlListItem(item).Render(ctx, templ_7745c5c3_Buffer)
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				if len(props.RecentShortURLs) == 0 {
+					templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<div class=\"py-8 text-center text-sm text-zinc-500\">Links you shorten will show up here.</div>")
+					if templ_7745c5c3_Err != nil {
+						return templ_7745c5c3_Err
+					}
+				}
+				templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</div></div></main>")
 				if templ_7745c5c3_Err != nil {
 					return templ_7745c5c3_Err
 				}
@@ -143,7 +203,64 @@ func Homepage() templ.Component {
 	})
 }
 
-func homepageShortUrlListItem() templ.Component {
+// homepageChallenge solves the proof-of-work challenge in the background as
+// soon as the page loads, holding back the form until a solution is found.
+func homepageChallenge(props HomepageProps) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var7 == nil {
+			templ_7745c5c3_Var7 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<input type=\"hidden\" name=\"challenge\" value=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var8 string
+		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(props.Challenge)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 108, Col: 62}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "\" data-difficulty=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(props.ChallengeDifficulty))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 108, Col: 122}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "\"> <input type=\"hidden\" name=\"challenge_solution\"><noscript><p class=\"mb-4 text-sm text-red-600\">JavaScript is required to create links without signing in.</p></noscript><script>\n\t\t(function () {\n\t\t\tvar form = document.getElementById(\"shorten\");\n\t\t\tvar challenge = form.elements.challenge.value;\n\t\t\tvar difficulty = parseInt(form.elements.challenge.dataset.difficulty, 10);\n\t\t\tvar solved = false, submitted = false;\n\n\t\t\t// find a solution for which sha256(challenge + \":\" + solution)\n\t\t\t// starts with difficulty zero bits, a slice at a time so the page\n\t\t\t// stays responsive\n\t\t\tvar solution = 0;\n\t\t\tfunction solve() {\n\t\t\t\tfor (var end = solution + 5000; solution < end; solution++) {\n\t\t\t\t\tif (Math.clz32(sha256(challenge + \":\" + solution)[0]) >= difficulty) {\n\t\t\t\t\t\tform.elements.challenge_solution.value = solution;\n\t\t\t\t\t\tsolved = true;\n\t\t\t\t\t\tif (submitted) {\n\t\t\t\t\t\t\tform.submit();\n\t\t\t\t\t\t}\n\t\t\t\t\t\treturn;\n\t\t\t\t\t}\n\t\t\t\t}\n\t\t\t\tsetTimeout(solve, 0);\n\t\t\t}\n\n\t\t\tform.addEventListener(\"submit\", function (e) {\n\t\t\t\tif (!solved) {\n\t\t\t\t\te.preventDefault();\n\t\t\t\t\tsubmitted = true;\n\t\t\t\t\tform.querySelector(\"button div\").textContent = \"Checking…\";\n\t\t\t\t}\n\t\t\t});\n\n\t\t\t// sha256 returns the hash of an ascii string as eight 32 bit words,\n\t\t\t// since crypto.subtle is only available over https\n\t\t\tvar K = [\n\t\t\t\t0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,\n\t\t\t\t0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,\n\t\t\t\t0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,\n\t\t\t\t0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,\n\t\t\t\t0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,\n\t\t\t\t0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,\n\t\t\t\t0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,\n\t\t\t\t0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2\n\t\t\t];\n\t\t\tfunction sha256(s) {\n\t\t\t\tvar n = s.length, words = new Array(((n + 8) >> 6 << 4) + 16).fill(0), w = new Array(64), i;\n\t\t\t\tfor (i = 0; i < n; i++) {\n\t\t\t\t\twords[i >> 2] |= s.charCodeAt(i) << (24 - (i & 3) * 8);\n\t\t\t\t}\n\t\t\t\twords[n >> 2] |= 0x80 << (24 - (n & 3) * 8);\n\t\t\t\twords[words.length - 1] = n * 8;\n\n\t\t\t\tvar h = [0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a, 0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19];\n\t\t\t\tfor (var j = 0; j < words.length; j += 16) {\n\t\t\t\t\tvar a = h[0], b = h[1], c = h[2], d = h[3], e = h[4], f = h[5], g = h[6], k = h[7];\n\t\t\t\t\tfor (i = 0; i < 64; i++) {\n\t\t\t\t\t\tif (i < 16) {\n\t\t\t\t\t\t\tw[i] = words[j + i];\n\t\t\t\t\t\t} else {\n\t\t\t\t\t\t\tvar x = w[i - 15], y = w[i - 2];\n\t\t\t\t\t\t\tw[i] = (((x >>> 7 | x << 25) ^ (x >>> 18 | x << 14) ^ (x >>> 3)) + w[i - 16] +\n\t\t\t\t\t\t\t\t((y >>> 17 | y << 15) ^ (y >>> 19 | y << 13) ^ (y >>> 10)) + w[i - 7]) | 0;\n\t\t\t\t\t\t}\n\t\t\t\t\t\tvar t1 = (k + ((e >>> 6 | e << 26) ^ (e >>> 11 | e << 21) ^ (e >>> 25 | e << 7)) + ((e & f) ^ (~e & g)) + K[i] + w[i]) | 0;\n\t\t\t\t\t\tvar t2 = (((a >>> 2 | a << 30) ^ (a >>> 13 | a << 19) ^ (a >>> 22 | a << 10)) + ((a & b) ^ (a & c) ^ (b & c))) | 0;\n\t\t\t\t\t\tk = g; g = f; f = e; e = (d + t1) | 0; d = c; c = b; b = a; a = (t1 + t2) | 0;\n\t\t\t\t\t}\n\t\t\t\t\th[0] = (h[0] + a) | 0; h[1] = (h[1] + b) | 0; h[2] = (h[2] + c) | 0; h[3] = (h[3] + d) | 0;\n\t\t\t\t\th[4] = (h[4] + e) | 0; h[5] = (h[5] + f) | 0; h[6] = (h[6] + g) | 0; h[7] = (h[7] + k) | 0;\n\t\t\t\t}\n\t\t\t\treturn h;\n\t\t\t}\n\n\t\t\tsolve();\n\t\t})();\n\t</script>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+func homepageShortUrlListItem(item HomepageShortURL) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -159,12 +276,101 @@ func homepageShortUrlListItem() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var5 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var5 == nil {
-			templ_7745c5c3_Var5 = templ.NopComponent
+		templ_7745c5c3_Var10 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var10 == nil {
+			templ_7745c5c3_Var10 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "<div class=\"py-6 flex gap-4 text-sm items-center justify-between\"><div class=\"flex gap-4 items-center\"><div class=\"size-12 border rounded-lg border-zinc-200 dark:border-zinc-800 overflow-hidden\"><img src=\"https://icon.horse/icon/suss-production.up.railway.app\" class=\"size-12\" alt=\"Icon\"></div><div><div class=\"font-semibold\">Title</div><a class=\"text-blue-600 hover:underline\" href=\"#\">https://example.com</a></div></div><div class=\"text-zinc-500\">0 visits</div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, "<div class=\"py-6 flex gap-4 text-sm items-center justify-between\"><div class=\"flex gap-4 items-center min-w-0\"><div class=\"size-12 shrink-0 border rounded-lg border-zinc-200 dark:border-zinc-800 overflow-hidden\"><img src=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var11 string
+		templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("https://icon.horse/icon/%s", item.ShortURL.Host()))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 196, Col: 78}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "\" class=\"size-12\" alt=\"Icon\"></div><div class=\"min-w-0\"><a class=\"font-semibold hover:underline\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var12 templ.SafeURL
+		templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL(item.ManageURL))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 199, Col: 81}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var13 string
+		templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinStringErrs(item.Url)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 199, Col: 94}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "</a> <a class=\"block truncate text-blue-600 hover:underline\" href=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var14 templ.SafeURL
+		templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinURLErrs(templ.URL(item.ShortURL.LongURL))
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 200, Col: 99}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var15 string
+		templ_7745c5c3_Var15, templ_7745c5c3_Err = templ.JoinStringErrs(item.ShortURL.LongURL)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 200, Col: 125}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var15))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "</a></div></div><div class=\"text-zinc-500 whitespace-nowrap\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if item.Visits == 1 {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, "1 visit")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		} else {
+			var templ_7745c5c3_Var16 string
+			templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(strconv.Itoa(item.Visits))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `http/html/homepage.templ`, Line: 207, Col: 31}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, " visits")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "</div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
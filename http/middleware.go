@@ -1,10 +1,303 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// Requests allowed per minute for each ip address, or for each API key.
+const (
+	RateLimit       = 100
+	APIKeyRateLimit = 600
 )
 
+// redactedQueryParams are query string parameters never written to the logs.
+var redactedQueryParams = []string{"secret", "password", "token"}
+
+// redactingLogFormatter wraps a request log formatter, replacing the values of
+// sensitive query string parameters before the request is logged.
+type redactingLogFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f *redactingLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	q := r.URL.Query()
+
+	var redacted bool
+	for _, name := range redactedQueryParams {
+		if q.Has(name) {
+			q.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if redacted {
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = q.Encode()
+		r.RequestURI = r.URL.RequestURI()
+	}
+
+	return f.LogFormatter.NewLogEntry(r)
+}
+
+// middlewareAuthenticate adds the signed in user, if any, to the request
+// context. API requests are only authenticated with an API key passed in an
+// "Authorization: Bearer" header, never by the session cookie, so they need
+// no CSRF token.
+func (s *Server) middlewareAuthenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			s.authenticateAPIKey(w, r, next)
+			return
+		}
+
+		token := s.session(r).Token
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// expired or revoked sessions are treated as anonymous
+		session, err := s.SessionService.FindSessionByToken(r.Context(), token)
+		if suss.ErrorCode(err) == suss.ENOTFOUND {
+			next.ServeHTTP(w, r)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(suss.NewContextWithUser(r.Context(), session.User)))
+	})
+}
+
+// authenticateAPIKey adds the API key of the bearer authorization header, and
+// its user, to the request context. Requests without one are anonymous.
+func (s *Server) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	v := r.Header.Get("Authorization")
+	if v == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	// failed authentications count against the rate limit of the ip address,
+	// which is checked before looking the key up
+	ipKey, err := rateLimitIPKey(r)
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	} else if _, rate, err := s.rateLimiter.Status(ipKey); err != nil {
+		s.Error(w, r, err)
+		return
+	} else if rate >= RateLimit {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	bearer, ok := strings.CutPrefix(v, "Bearer ")
+	if !ok {
+		s.rateLimiter.OnLimit(w, r, ipKey)
+		s.Error(w, r, suss.Errorf(suss.EUNAUTHORIZED, "Invalid authorization header, expected a bearer API key."))
+		return
+	}
+
+	key, err := s.APIKeyService.Authenticate(r.Context(), strings.TrimSpace(bearer))
+	if err != nil {
+		s.rateLimiter.OnLimit(w, r, ipKey)
+		s.Error(w, r, err)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(suss.NewContextWithAPIKey(r.Context(), key)))
+}
+
+// middlewareCSRF rejects form posts without the CSRF token stored in the
+// session cookie. The token is added to the request context for html forms to
+// render. Visitors are only issued a token once a page renders a form, so
+// plain redirects and images set no cookie. API routes are exempt as they are
+// never authenticated by the cookie.
+func (s *Server) middlewareCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		session := s.session(r)
+		token := func() string { return session.CSRFToken }
+
+		var cw *csrfResponseWriter
+		if session.CSRFToken == "" {
+			session.CSRFToken = rand.Text()
+
+			// handlers read the session from the request, make sure they
+			// see the new token so it is kept when they update the cookie
+			if err := s.replaceRequestSession(r, session); err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			cw = &csrfResponseWriter{ResponseWriter: w, server: s, r: r, session: session}
+			w, token = cw, cw.Token
+		}
+		r = r.WithContext(html.NewContextWithCSRFToken(r.Context(), token))
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+		default:
+			formToken := r.PostFormValue(html.CSRFFieldName)
+			if formToken == "" {
+				formToken = r.Header.Get("X-CSRF-Token")
+			}
+			if subtle.ConstantTimeCompare([]byte(formToken), []byte(session.CSRFToken)) != 1 {
+				s.csrfError(w, r)
+			} else {
+				next.ServeHTTP(w, r)
+			}
+		}
+
+		if cw != nil {
+			if err := cw.flush(); err != nil {
+				log.Printf("cannot write response: %s", err)
+			}
+		}
+	})
+}
+
+// csrfResponseWriter holds back pages sent to a visitor without a CSRF token
+// until the handler returns, so a token asked for while rendering a form can
+// still be issued in the session cookie. Other responses, such as images, are
+// written straight away.
+type csrfResponseWriter struct {
+	http.ResponseWriter
+	server  *Server
+	r       *http.Request
+	session Session
+
+	issued      bool // a form asked for the token
+	status      int
+	buf         bytes.Buffer
+	passthrough bool
+}
+
+// Token returns the CSRF token of the visitor, issuing it with the response.
+func (w *csrfResponseWriter) Token() string {
+	w.issued = true
+	return w.session.CSRFToken
+}
+
+func (w *csrfResponseWriter) WriteHeader(status int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(status)
+	} else if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *csrfResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	// only html pages render forms
+	if ct := w.Header().Get("Content-Type"); !w.passthrough && ct != "" && !strings.HasPrefix(ct, "text/html") {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *csrfResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flush writes the held back response, along with the session cookie if the
+// token was issued and the handler did not set the cookie itself.
+func (w *csrfResponseWriter) flush() error {
+	if w.passthrough {
+		return nil
+	}
+
+	if w.issued && !hasSetCookie(w.Header(), SessionCookieName) {
+		if err := w.server.setSession(w.ResponseWriter, w.r, w.session); err != nil {
+			return err
+		}
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+// hasSetCookie returns true if header sets the cookie with the given name.
+func hasSetCookie(header http.Header, name string) bool {
+	for _, v := range header.Values("Set-Cookie") {
+		if strings.HasPrefix(v, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// middlewareRateLimit limits requests by ip address. Requests authenticated
+// with an API key are instead limited per key, with a higher limit, so
+// clients sharing an address do not affect each other.
+func (s *Server) middlewareRateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var limitKey string
+		if key := suss.APIKeyFromContext(r.Context()); key != nil {
+			limitKey = "key:" + strconv.Itoa(key.ID)
+			r = r.WithContext(httprate.WithRequestLimit(r.Context(), APIKeyRateLimit))
+		} else {
+			ipKey, err := rateLimitIPKey(r)
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+			limitKey = ipKey
+		}
+
+		if s.rateLimiter.RespondOnLimit(w, r, limitKey) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitIPKey returns the rate limit key of the ip address of r.
+func rateLimitIPKey(r *http.Request) (string, error) {
+	ip, err := httprate.KeyByIP(r)
+	return "ip:" + ip, err
+}
+
+// middlewareRequestInfo adds the request ID and a hash of the client IP to the
+// request context, so changes made by the request can be audited.
+func (s *Server) middlewareRequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := suss.NewContextWithRequestInfo(r.Context(), &suss.RequestInfo{
+			ID:     middleware.GetReqID(r.Context()),
+			IPHash: s.hash(s.RemoteIP(r)),
+			Host:   s.Host(r),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (s *Server) middlewareHost(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.WithValue(r.Context(), "url", s.PublicURL(r))
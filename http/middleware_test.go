@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// countingAPIKeyService counts calls to Authenticate.
+type countingAPIKeyService struct {
+	suss.APIKeyService
+	n atomic.Int64
+}
+
+func (s *countingAPIKeyService) Authenticate(ctx context.Context, key string) (*suss.APIKey, error) {
+	s.n.Add(1)
+	return s.APIKeyService.Authenticate(ctx, key)
+}
+
+func TestServer_RateLimitInvalidAPIKeys(t *testing.T) {
+	var keys *countingAPIKeyService
+	s := MustOpenServer(t, func(s *TestServer) {
+		keys = &countingAPIKeyService{APIKeyService: s.APIKeyService}
+		s.APIKeyService = keys
+	})
+	c := s.NewClient(t)
+
+	// invalid keys are throttled like anonymous requests of the ip address,
+	// before they are looked up
+	var unauthorized, limited int
+	for i := 0; i < RateLimit+20; i++ {
+		req, err := http.NewRequest(http.MethodGet, s.URL()+"/api/v1/short-urls", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer suss_bogus")
+
+		switch resp, _ := c.Do(req); resp.StatusCode {
+		case http.StatusUnauthorized:
+			unauthorized++
+		case http.StatusTooManyRequests:
+			limited++
+		default:
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	}
+
+	if unauthorized != RateLimit || limited != 20 {
+		t.Fatalf("unauthorized=%d, limited=%d", unauthorized, limited)
+	} else if n := keys.n.Load(); n != RateLimit {
+		t.Fatalf("lookups=%d, want %d", n, RateLimit)
+	}
+
+	// the address is limited for anonymous requests as well
+	if resp, _ := c.Get("/"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("StatusCode=%d", resp.StatusCode)
+	}
+}
+
+func TestServer_CSRF(t *testing.T) {
+	s := MustOpenServer(t, nil)
+	shortUrl := &suss.ShortURL{LongURL: "https://example.com/"}
+	if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("NoFormNoCookie", func(t *testing.T) {
+		// redirects and images render no form, so no token is issued
+		c := s.NewClient(t)
+		for _, path := range []string{"/" + shortUrl.Slug, "/qrcode/" + shortUrl.Slug + ".png"} {
+			if resp, _ := c.Get(path); resp.StatusCode >= 400 {
+				t.Fatalf("%s: StatusCode=%d", path, resp.StatusCode)
+			} else if v := resp.Header.Values("Set-Cookie"); len(v) != 0 {
+				t.Fatalf("%s: Set-Cookie=%q", path, v)
+			}
+		}
+	})
+
+	t.Run("FormIssuesToken", func(t *testing.T) {
+		c := s.NewClient(t)
+		resp, body := c.Get("/")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !hasSetCookie(resp.Header, SessionCookieName) {
+			t.Fatal("expected session cookie")
+		} else if m := csrfFieldRegexp.FindStringSubmatch(body); m == nil {
+			t.Fatal("csrf token not found")
+		}
+
+		// the rendered token is the one stored in the cookie
+		if resp, _ := c.PostForm("/shorten", url.Values{"url": {"https://example.com/other"}}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrTokenMissing", func(t *testing.T) {
+		c := s.NewClient(t)
+		c.CSRFToken()
+
+		req, err := http.NewRequest(http.MethodPost, s.URL()+"/shorten", strings.NewReader(url.Values{"url": {"https://example.com/other"}}.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if resp, body := c.Do(req); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "This form has expired") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("ErrTokenMismatch", func(t *testing.T) {
+		// a token from another visitor's session is rejected
+		other := s.NewClient(t).CSRFToken()
+		c := s.NewClient(t)
+		c.CSRFToken()
+
+		form := url.Values{"url": {"https://example.com/other"}, html.CSRFFieldName: {other}}
+		req, err := http.NewRequest(http.MethodPost, s.URL()+"/shorten", strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if resp, _ := c.Do(req); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("APIExempt", func(t *testing.T) {
+		if resp, body := s.NewClient(t).PostJSON("/api/v1/short-urls", `{"long_url":"https://example.com/api"}`); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+	})
+}
+
+func TestRedactingLogFormatter(t *testing.T) {
+	var buf bytes.Buffer
+	f := &redactingLogFormatter{
+		LogFormatter: &middleware.DefaultLogFormatter{Logger: log.New(&buf, "", 0), NoColor: true},
+	}
+
+	r, err := http.NewRequest(http.MethodGet, "http://sus.example/manage/abc?secret=s3cr3t&password=hunter22&token=t0k3n&page=2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RequestURI = r.URL.RequestURI()
+	f.NewLogEntry(r).Write(http.StatusOK, 0, nil, 0, nil)
+
+	if line := buf.String(); strings.Contains(line, "s3cr3t") || strings.Contains(line, "hunter22") || strings.Contains(line, "t0k3n") {
+		t.Fatalf("sensitive value logged: %s", line)
+	} else if !strings.Contains(line, "secret=REDACTED") || !strings.Contains(line, "page=2") {
+		t.Fatalf("unexpected line: %s", line)
+	}
+
+	// the request itself is left untouched for the handlers
+	if got := r.URL.Query().Get("secret"); got != "s3cr3t" {
+		t.Fatalf("secret=%q", got)
+	}
+}
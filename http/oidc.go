@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+	"golang.org/x/oauth2"
+)
+
+// OIDCTimeout is the timeout of requests made to the OpenID Connect provider.
+const OIDCTimeout = 10 * time.Second
+
+// OIDCEnabled returns true if single sign-on is configured.
+func (s *Server) OIDCEnabled() bool {
+	return s.oidcProvider != nil
+}
+
+// openOIDC discovers the endpoints & keys of the configured OpenID Connect
+// provider. Single sign-on is disabled without an issuer.
+func (s *Server) openOIDC() error {
+	if s.OIDCIssuer == "" {
+		return nil
+	}
+
+	// the context is kept by the provider to fetch signing keys later on
+	ctx := oidc.ClientContext(context.Background(), &http.Client{Timeout: OIDCTimeout})
+	provider, err := oidc.NewProvider(ctx, s.OIDCIssuer)
+	if err != nil {
+		return err
+	}
+	s.oidcProvider = provider
+
+	return nil
+}
+
+// oauth2Config returns the OAuth 2.0 client configuration of the provider,
+// redirecting back to the host of the request.
+func (s *Server) oauth2Config(r *http.Request) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     s.OIDCClientID,
+		ClientSecret: s.OIDCClientSecret,
+		Endpoint:     s.oidcProvider.Endpoint(),
+		RedirectURL:  s.PublicURL(r) + "/login/oidc/callback",
+		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	}
+}
+
+func (s *Server) handlerOIDCLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.OIDCEnabled() {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Single sign-on is not enabled."))
+			return
+		}
+
+		// bind the callback to this browser, the state guards against forged
+		// callbacks, the nonce against replayed tokens and the pkce verifier
+		// against intercepted codes
+		pending := &SessionOIDC{
+			State:    rand.Text(),
+			Nonce:    rand.Text(),
+			Verifier: oauth2.GenerateVerifier(),
+			Next:     localRedirect(r.URL.Query().Get("next")),
+		}
+
+		session := s.session(r)
+		session.OIDC = pending
+		if err := s.setSession(w, r, session); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, s.oauth2Config(r).AuthCodeURL(
+			pending.State,
+			oidc.Nonce(pending.Nonce),
+			oauth2.S256ChallengeOption(pending.Verifier),
+		), http.StatusFound)
+	}
+}
+
+func (s *Server) handlerOIDCCallback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.OIDCEnabled() {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Single sign-on is not enabled."))
+			return
+		}
+
+		session := s.session(r)
+		pending := session.OIDC
+
+		user, err := s.oidcAuthenticate(r, pending)
+		if code := suss.ErrorCode(err); code == suss.EUNAUTHORIZED || code == suss.ECONFLICT {
+			// the pending sign in can only be used once
+			session.OIDC = nil
+			if err := s.setSession(w, r, session); err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			w.WriteHeader(ErrorStatusCode(code))
+			html.LoginPage(html.LoginPageProps{
+				Error:       suss.ErrorMessage(err),
+				OIDCEnabled: true,
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if err := s.login(w, r, user); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, localRedirect(pending.Next), http.StatusSeeOther)
+	}
+}
+
+// oidcAuthenticate exchanges the code of a provider callback for a verified ID
+// token and returns its user, provisioning them on first sign in. Signed in
+// users link the identity to their account instead.
+func (s *Server) oidcAuthenticate(r *http.Request, pending *SessionOIDC) (*suss.User, error) {
+	q := r.URL.Query()
+	if v := q.Get("error"); v != "" {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed: %s.", v)
+	} else if pending == nil || subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(pending.State)) != 1 {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on expired, please try again.")
+	}
+
+	ctx := oidc.ClientContext(r.Context(), &http.Client{Timeout: OIDCTimeout})
+	token, err := s.oauth2Config(r).Exchange(ctx, q.Get("code"), oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed, the provider rejected the sign in.")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed, the provider did not return an ID token.")
+	}
+
+	idToken, err := s.oidcProvider.Verifier(&oidc.Config{ClientID: s.OIDCClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed, invalid ID token.")
+	} else if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(pending.Nonce)) != 1 {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed, invalid ID token.")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Single sign-on failed, invalid ID token.")
+	}
+
+	// new users are provisioned with the email, so it must be one the
+	// provider vouches for. a missing email_verified claim is not a vouch
+	if claims.Email == "" {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Your identity provider did not share your email address.")
+	} else if !claims.EmailVerified {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Your email address is not verified by your identity provider.")
+	}
+	email, err := suss.NormalizeEmail(claims.Email)
+	if err != nil {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Your identity provider shared an invalid email address.")
+	} else if !s.isOIDCAllowedEmail(email) {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Accounts with this email domain cannot sign in.")
+	}
+
+	// name new users after their profile, falling back to their email
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	identity := &suss.Identity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		User:    &suss.User{Name: name, Email: email},
+	}
+	if err := s.IdentityService.CreateIdentity(r.Context(), identity); err != nil {
+		return nil, err
+	}
+	return identity.User, nil
+}
+
+// isOIDCAllowedEmail returns true if email is in one of the allowed domains, or
+// if any domain is allowed.
+func (s *Server) isOIDCAllowedEmail(email string) bool {
+	if len(s.OIDCAllowedDomains) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.ContainsFunc(s.OIDCAllowedDomains, func(v string) bool {
+		return strings.EqualFold(v, domain)
+	})
+}
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// OIDCProvider is a minimal OpenID Connect provider serving discovery, keys,
+// and the authorization code flow with PKCE. Every authorization signs the
+// user in with the claims set by SetClaims.
+type OIDCProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]oidcAuthorization
+	claims map[string]any
+}
+
+// oidcAuthorization is an authorization code waiting to be exchanged.
+type oidcAuthorization struct {
+	nonce     string
+	challenge string
+	claims    map[string]any
+}
+
+// MustOpenOIDCProvider returns a running OIDCProvider.
+func MustOpenOIDCProvider(tb testing.TB) *OIDCProvider {
+	tb.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	p := &OIDCProvider{key: key, codes: make(map[string]oidcAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /jwks", p.handleJWKS)
+	mux.HandleFunc("GET /authorize", p.handleAuthorize)
+	mux.HandleFunc("POST /token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+	tb.Cleanup(p.Close)
+	return p
+}
+
+// SetClaims sets the claims of the ID tokens issued from now on.
+func (p *OIDCProvider) SetClaims(claims map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+func (p *OIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *OIDCProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]any{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *OIDCProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "pkce required", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	code := rand.Text()
+	p.codes[code] = oidcAuthorization{
+		nonce:     q.Get("nonce"),
+		challenge: q.Get("code_challenge"),
+		claims:    p.claims,
+	}
+	p.mu.Unlock()
+
+	redirectURL, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	redirectURL.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+func (p *OIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	// the verifier must match the challenge sent when authorizing
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]any{
+		"iss":   p.URL,
+		"aud":   "suss",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": auth.nonce,
+	}
+	for k, v := range auth.claims {
+		claims[k] = v
+	}
+
+	idToken, err := p.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// sign returns claims as a JWT signed with RS256.
+func (p *OIDCProvider) sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// LoginOIDC signs c in through the provider and returns the callback response.
+func (c *Client) LoginOIDC() (*http.Response, string) {
+	c.tb.Helper()
+	resp, _ := c.Get("/login/oidc")
+	resp, _ = c.Follow(resp) // the provider redirects back to the callback
+	return c.Follow(resp)
+}
+
+func TestServer_OIDC(t *testing.T) {
+	// MustOpen returns a server using a new provider.
+	MustOpen := func(tb testing.TB) (*TestServer, *OIDCProvider) {
+		provider := MustOpenOIDCProvider(tb)
+		s := MustOpenServer(tb, func(s *TestServer) {
+			s.OIDCIssuer, s.OIDCClientID = provider.URL, "suss"
+		})
+		return s, provider
+	}
+
+	t.Run("Provision", func(t *testing.T) {
+		s, provider := MustOpen(t)
+		provider.SetClaims(map[string]any{"sub": "1", "email": "susy@example.com", "email_verified": true, "name": "Susy"})
+
+		c := s.NewClient(t)
+		if resp, body := c.LoginOIDC(); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+		if users, _, err := s.UserService.FindUsers(adminContext(), suss.UserFilter{}); err != nil {
+			t.Fatal(err)
+		} else if len(users) != 1 || users[0].Email != "susy@example.com" || users[0].Name != "Susy" {
+			t.Fatalf("unexpected users: %#v", users)
+		}
+	})
+
+	t.Run("ErrEmailVerifiedMissing", func(t *testing.T) {
+		s, provider := MustOpen(t)
+		provider.SetClaims(map[string]any{"sub": "1", "email": "susy@example.com"})
+
+		c := s.NewClient(t)
+		if resp, body := c.LoginOIDC(); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "not verified") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("ErrExistingAccount", func(t *testing.T) {
+		s, provider := MustOpen(t)
+		if err := s.UserService.Create(context.Background(), &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// whoever controls the email at the provider cannot take the account over
+		provider.SetClaims(map[string]any{"sub": "1", "email": "susy@example.com", "email_verified": true})
+		c := s.NewClient(t)
+		if resp, body := c.LoginOIDC(); resp.StatusCode != http.StatusConflict {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "already exists") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+
+	t.Run("LinkSignedIn", func(t *testing.T) {
+		s, provider := MustOpen(t)
+		if err := s.UserService.Create(context.Background(), &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"}); err != nil {
+			t.Fatal(err)
+		}
+
+		// sign in with the password, then with the provider to link it
+		c := s.NewClient(t)
+		if resp, _ := c.PostForm("/login", url.Values{"email": {"susy@example.com"}, "password": {"password123"}}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		provider.SetClaims(map[string]any{"sub": "1", "email": "susy@corp.example", "email_verified": true})
+		if resp, body := c.LoginOIDC(); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+
+		// the linked identity signs in to the account from any browser
+		if resp, body := s.NewClient(t).LoginOIDC(); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+		if _, n, err := s.UserService.FindUsers(adminContext(), suss.UserFilter{}); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d", n)
+		}
+	})
+}
+
+// adminContext returns a context of an administrator, such as one signed in
+// with the admin token.
+func adminContext() context.Context {
+	return suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+}
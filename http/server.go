@@ -2,16 +2,23 @@ package http
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/a-h/templ"
 	"github.com/benbjohnson/hashfs"
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
+	"github.com/gorilla/securecookie"
 	"github.com/heyjorgedev/suss"
 	"github.com/heyjorgedev/suss/http/dist"
 	"github.com/heyjorgedev/suss/http/html"
@@ -24,49 +31,158 @@ type Server struct {
 	ln     net.Listener
 	server *http.Server
 	router chi.Router
+	sc     *securecookie.SecureCookie
+
+	// discovered provider, nil unless single sign-on is configured
+	oidcProvider *oidc.Provider
+
+	// decoded HashKey
+	hashKey []byte
+
+	// solved challenges & the rate of anonymous link creation
+	challenger *challenger
+
+	// requests per ip address or API key, see middlewareRateLimit
+	rateLimiter *httprate.RateLimiter
 
 	// address to listen on
 	Addr string
 
+	// hex encoded keys used to sign & encrypt cookies. the hash key is also
+	// used to hash visitor identifiers, such as ip addresses
+	HashKey       string
+	EncryptionKey string
+
+	// optional OpenID Connect provider used for single sign-on. Users are
+	// provisioned on first sign in if their email is in one of the allowed
+	// domains, or in any domain if none are set. Existing accounts link the
+	// provider by signing in with it while signed in.
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCAllowedDomains []string
+
+	// optional token granting access to /admin without an administrator
+	// account, e.g. to set up a new instance
+	AdminToken string
+
+	// optional proof-of-work challenge anonymous visitors solve to create a
+	// link. Difficulty is the number of leading zero bits required, zero to
+	// disable. It rises by a bit each time anonymous creations per minute
+	// double past SpikeThreshold, if set.
+	ChallengeDifficulty     int
+	ChallengeSpikeThreshold int
+
 	// dependent services to use
-	ShortURLService suss.ShortURLService
+	AbuseReportService suss.AbuseReportService
+	APIKeyService      suss.APIKeyService
+	AuditService       suss.AuditService
+	ClickService       suss.ClickService
+	DomainRuleService  suss.DomainRuleService
+	IdentityService    suss.IdentityService
+	SessionService     suss.SessionService
+	ShortURLService    suss.ShortURLService
+	StatsService       suss.StatsService
+	UserService        suss.UserService
+	WorkspaceService   suss.WorkspaceService
+
+	// optional mailer, manage links cannot be recovered by email without it
+	Mailer suss.Mailer
+
+	// public base url links sent by email point to, e.g. "https://example.com".
+	// Required with a mailer, as the request headers can be set by anyone.
+	BaseURL string
 }
 
 func NewServer() *Server {
 	r := chi.NewRouter()
 	s := &Server{
-		server: &http.Server{},
-		router: r,
+		server:      &http.Server{},
+		router:      r,
+		challenger:  newChallenger(),
+		rateLimiter: httprate.NewRateLimiter(RateLimit, time.Minute),
 	}
 	s.server.Handler = http.HandlerFunc(s.serveHTTP)
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestLogger(&redactingLogFormatter{
+		LogFormatter: &middleware.DefaultLogFormatter{Logger: log.New(os.Stdout, "", log.LstdFlags), NoColor: true},
+	}))
 	r.Use(middleware.GetHead)
-	r.Use(httprate.LimitByIP(100, time.Minute))
 	r.Use(s.middlewareHost)
 	r.Use(middleware.Recoverer)
+	r.Use(s.middlewareRequestInfo)
+	r.Use(s.middlewareAuthenticate)
+	r.Use(s.middlewareRateLimit)
+	r.Use(s.middlewareCSRF)
 
 	// setup a timeout
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	r.Handle("/assets/*", http.StripPrefix("/assets/", hashfs.FileServer(dist.FS)))
-	r.NotFound(templ.Handler(html.NotFoundPage()).ServeHTTP)
+	r.NotFound(templ.Handler(html.NotFoundPage(), templ.WithStatus(http.StatusNotFound)).ServeHTTP)
 
 	// register routes
+	// note: new top-level paths must also be added to the reserved slugs in the suss package
 	r.Get("/", s.handlerHomepage())
 	r.Post("/shorten", s.handlerShortUrlCreate())
 	r.Get("/preview/{slug}", s.handlerShortUrlPreview())
 	r.Get("/manage/{slug}", s.handlerShortUrlManage())
+	r.Post("/manage/{slug}/unlock", s.handlerShortUrlManageUnlock())
+	r.Post("/manage/{slug}/rotate", s.handlerShortUrlRotateSecret())
+	r.Post("/manage/{slug}/recover", s.handlerShortUrlRecover())
+	r.Get("/manage/{slug}/redeem", s.handlerShortUrlRedeem())
+	r.Post("/manage/{slug}/redeem", s.handlerShortUrlRedeemSubmit())
+	r.Patch("/manage/{slug}", s.handlerShortUrlUpdate())
+	r.Delete("/manage/{slug}", s.handlerShortUrlDelete())
 	r.Get("/qrcode/{slug}.png", s.handlerShortUrlQrCode())
+	r.Get("/report/{slug}", s.handlerReport())
+	r.Post("/report/{slug}", s.handlerReportSubmit())
+	r.Get("/links", s.handlerUserShortUrls())
+	r.Get("/api-keys", s.handlerAPIKeys())
+	r.Post("/api-keys", s.handlerAPIKeyCreate())
+	r.Delete("/api-keys/{id}", s.handlerAPIKeyDelete())
+	r.Get("/login", s.handlerLogin())
+	r.Post("/login", s.handlerLoginSubmit())
+	r.Get("/login/oidc", s.handlerOIDCLogin())
+	r.Get("/login/oidc/callback", s.handlerOIDCCallback())
+	r.Post("/logout", s.handlerLogout())
+	r.Get("/register", s.handlerRegister())
+	r.Post("/register", s.handlerRegisterSubmit())
+	r.Get("/workspaces", s.handlerWorkspaces())
+	r.Post("/workspaces", s.handlerWorkspaceCreate())
+	r.Post("/workspaces/switch", s.handlerWorkspaceSwitch())
+	r.Get("/workspaces/{id}", s.handlerWorkspace())
+	r.Delete("/workspaces/{id}", s.handlerWorkspaceDelete())
+	r.Post("/workspaces/{id}/members", s.handlerWorkspaceMemberInvite())
+	r.Patch("/workspaces/{id}/members/{memberID}", s.handlerWorkspaceMemberUpdate())
+	r.Delete("/workspaces/{id}/members/{memberID}", s.handlerWorkspaceMemberRemove())
+	s.registerAPIRoutes(r)
+	s.registerAdminRoutes(r)
 	r.Get("/{slug}+", s.handlerShortUrlPreview())
 	r.Get("/{slug}", s.handlerShortUrlVisit())
+	r.Post("/{slug}", s.handlerShortUrlUnlock())
 
 	return s
 }
 
 func (s *Server) Open() (err error) {
+	// prepare the keys used to secure cookies
+	if err := s.openSecureCookie(); err != nil {
+		return err
+	}
+
+	// emailed links are only built from the configured base url
+	if s.Mailer != nil && s.BaseURL == "" {
+		return fmt.Errorf("base url required to send email")
+	}
+
+	// discover the single sign-on provider, if any
+	if err := s.openOIDC(); err != nil {
+		return err
+	}
+
 	// open a listener on our bind address.
 	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
 		return err
@@ -96,6 +212,21 @@ func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
 }
 
+// hash returns a hex encoded HMAC-SHA256 of v keyed by the server's HashKey.
+func (s *Server) hash(v string) string {
+	h := hmac.New(sha256.New, s.hashKey)
+	h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RemoteIP returns the ip address of the client without the port.
+func (s *Server) RemoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
 func (s *Server) Scheme(r *http.Request) string {
 	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
 		return proto
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+	"github.com/heyjorgedev/suss/sqlite"
+)
+
+const (
+	testHashKey       = "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+	testEncryptionKey = "0123456789abcdef0123456789abcdef"
+)
+
+// TestServer is a running Server backed by a temporary database.
+type TestServer struct {
+	*Server
+	DB *sqlite.DB
+}
+
+// MustOpenServer returns a TestServer bound to a random local port. The
+// configure function, if any, is called before the server is opened.
+func MustOpenServer(tb testing.TB, configure func(s *TestServer)) *TestServer {
+	tb.Helper()
+
+	db := sqlite.NewDB(filepath.Join(tb.TempDir(), "db"))
+	db.HashKey = []byte(testHashKey)
+	db.EncryptionKey = []byte(testEncryptionKey)
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+
+	s := &TestServer{Server: NewServer(), DB: db}
+	s.Addr = "127.0.0.1:0"
+	s.HashKey = testHashKey
+	s.EncryptionKey = testEncryptionKey
+	s.AbuseReportService = sqlite.NewAbuseReportService(db)
+	s.APIKeyService = sqlite.NewAPIKeyService(db)
+	s.AuditService = sqlite.NewAuditService(db)
+	s.ClickService = sqlite.NewClickService(db)
+	s.DomainRuleService = sqlite.NewDomainRuleService(db)
+	s.IdentityService = sqlite.NewIdentityService(db)
+	s.SessionService = sqlite.NewSessionService(db)
+	s.ShortURLService = sqlite.NewShortURLService(db)
+	s.StatsService = sqlite.NewStatsService(db)
+	s.UserService = sqlite.NewUserService(db)
+	s.WorkspaceService = sqlite.NewWorkspaceService(db)
+	if configure != nil {
+		configure(s)
+	}
+
+	if err := s.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		s.Close()
+		db.Close()
+	})
+	return s
+}
+
+// URL returns the base url of the running server.
+func (s *TestServer) URL() string {
+	return "http://" + s.ln.Addr().String()
+}
+
+// Client is a browser-like client keeping cookies between requests. It does
+// not follow redirects so they can be checked.
+type Client struct {
+	*http.Client
+	tb      testing.TB
+	baseURL string
+}
+
+// NewClient returns a new Client for s, without a session.
+func (s *TestServer) NewClient(tb testing.TB) *Client {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return &Client{
+		Client: &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		tb:      tb,
+		baseURL: s.URL(),
+	}
+}
+
+// Get requests path and returns the response and its body.
+func (c *Client) Get(path string) (*http.Response, string) {
+	c.tb.Helper()
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+// Follow requests the location resp redirects to.
+func (c *Client) Follow(resp *http.Response) (*http.Response, string) {
+	c.tb.Helper()
+	if resp.StatusCode != http.StatusFound && resp.StatusCode != http.StatusSeeOther {
+		c.tb.Fatalf("StatusCode=%d, expected a redirect", resp.StatusCode)
+	}
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, location.String(), nil)
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+// PostForm posts form to path with the CSRF token of the client's session and
+// returns the response and its body.
+func (c *Client) PostForm(path string, form url.Values) (*http.Response, string) {
+	c.tb.Helper()
+	form.Set(html.CSRFFieldName, c.CSRFToken())
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.Do(req)
+}
+
+// Do sends req and returns the response and its body.
+func (c *Client) Do(req *http.Request) (*http.Response, string) {
+	c.tb.Helper()
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+var csrfFieldRegexp = regexp.MustCompile(`name="` + html.CSRFFieldName + `" value="([^"]+)"`)
+
+// CSRFToken returns the CSRF token of the client's session, starting one if
+// needed.
+func (c *Client) CSRFToken() string {
+	c.tb.Helper()
+	_, body := c.Get("/")
+	m := csrfFieldRegexp.FindStringSubmatch(body)
+	if m == nil {
+		c.tb.Fatal("csrf token not found")
+	}
+	return m[1]
+}
+
+// Mailer is a suss.Mailer keeping sent mails in memory.
+type Mailer struct {
+	mu    sync.Mutex
+	mails []*suss.Mail
+
+	// returned by SendMail instead of sending, if set
+	Err error
+}
+
+func (m *Mailer) SendMail(ctx context.Context, mail *suss.Mail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// Mails returns the mails sent so far.
+func (m *Mailer) Mails() []*suss.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*suss.Mail(nil), m.mails...)
+}
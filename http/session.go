@@ -0,0 +1,164 @@
+package http
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+)
+
+// SessionCookieName is the name of the cookie holding the visitor's session.
+const SessionCookieName = "session"
+
+// SessionMaxAge is the lifetime of the session cookie, in seconds. Both the
+// browser and securecookie enforce it.
+const SessionMaxAge = 60 * 60 * 24 * 365
+
+// MaxRecentShortURLs is the number of recently created links kept in the session.
+const MaxRecentShortURLs = 10
+
+// Session represents the data stored in the signed & encrypted session cookie.
+type Session struct {
+	// Links created or unlocked by the visitor, most recent first. The secrets
+	// stored here authorize the manage page so they never appear in urls.
+	RecentShortURLs []SessionShortURL `json:"recent_short_urls,omitempty"`
+
+	// Token of the signed in user's session, if any.
+	Token string `json:"token,omitempty"`
+
+	// Workspace the signed in user is working in, zero for their personal links.
+	WorkspaceID int `json:"workspace_id,omitempty"`
+
+	// Single sign-on in progress, checked when the provider redirects back.
+	OIDC *SessionOIDC `json:"oidc,omitempty"`
+
+	// Hash of the admin token the visitor signed in to /admin with, if any.
+	AdminTokenHash string `json:"admin_token_hash,omitempty"`
+
+	// Token every form post must include, see middlewareCSRF.
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+// SessionOIDC holds the values binding an OpenID Connect callback to the
+// browser which started the sign in.
+type SessionOIDC struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+
+	// Local path to return to once signed in.
+	Next string `json:"next"`
+}
+
+// SessionShortURL identifies a link created by the visitor and the secret
+// needed to manage it.
+type SessionShortURL struct {
+	Slug   string `json:"slug"`
+	Secret string `json:"secret"`
+}
+
+// AddRecentShortURL adds a link to the front of the recently created list,
+// dropping the oldest links once MaxRecentShortURLs is reached.
+func (s *Session) AddRecentShortURL(slug, secret string) {
+	recent := []SessionShortURL{{Slug: slug, Secret: secret}}
+	for _, v := range s.RecentShortURLs {
+		if v.Slug != slug && len(recent) < MaxRecentShortURLs {
+			recent = append(recent, v)
+		}
+	}
+	s.RecentShortURLs = recent
+}
+
+// ShortURLSecret returns the secret stored for slug, if any.
+func (s *Session) ShortURLSecret(slug string) (string, bool) {
+	for _, v := range s.RecentShortURLs {
+		if v.Slug == slug {
+			return v.Secret, true
+		}
+	}
+	return "", false
+}
+
+// openSecureCookie decodes the hex encoded hash & encryption keys and prepares
+// the codec used for cookies.
+func (s *Server) openSecureCookie() (err error) {
+	if s.hashKey, err = decodeKey("hash key", s.HashKey); err != nil {
+		return err
+	}
+
+	encryptionKey, err := decodeKey("encryption key", s.EncryptionKey)
+	if err != nil {
+		return err
+	} else if n := len(encryptionKey); n != 16 && n != 24 && n != 32 {
+		return fmt.Errorf("encryption key must be 16, 24 or 32 bytes, got %d", n)
+	}
+
+	s.sc = securecookie.New(s.hashKey, encryptionKey)
+	s.sc.SetSerializer(securecookie.JSONEncoder{})
+	s.sc.MaxAge(SessionMaxAge)
+	return nil
+}
+
+// decodeKey hex decodes a required key.
+func decodeKey(name, v string) ([]byte, error) {
+	if v == "" {
+		return nil, fmt.Errorf("%s required", name)
+	}
+
+	key, err := hex.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, expected hex: %w", name, err)
+	}
+	return key, nil
+}
+
+// session returns the session from the request cookie. An empty session is
+// returned if the cookie is missing or cannot be decoded.
+func (s *Server) session(r *http.Request) Session {
+	var session Session
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if err := s.sc.Decode(SessionCookieName, cookie.Value, &session); err != nil {
+			return Session{}
+		}
+	}
+	return session
+}
+
+// setSession writes the session to the response cookie.
+func (s *Server) setSession(w http.ResponseWriter, r *http.Request, session Session) error {
+	buf, err := s.sc.Encode(SessionCookieName, session)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    buf,
+		Path:     "/",
+		MaxAge:   SessionMaxAge,
+		Secure:   s.Scheme(r) == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// replaceRequestSession replaces the session cookie of the request so later
+// calls to session, within the same request, return the given session.
+func (s *Server) replaceRequestSession(r *http.Request, session Session) error {
+	buf, err := s.sc.Encode(SessionCookieName, session)
+	if err != nil {
+		return err
+	}
+
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != SessionCookieName {
+			r.AddCookie(cookie)
+		}
+	}
+	r.AddCookie(&http.Cookie{Name: SessionCookieName, Value: buf})
+	return nil
+}
@@ -2,7 +2,10 @@ package http
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,34 +17,126 @@ import (
 
 func (s *Server) handlerHomepage() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		html.Homepage().Render(r.Context(), w)
+		session := s.session(r)
+
+		// look up the links recently created by the visitor, forgetting about
+		// any which were deleted or can no longer be managed with the secret
+		var items []html.HomepageShortURL
+		var recent []SessionShortURL
+		for _, v := range session.RecentShortURLs {
+			shortUrl, err := s.ShortURLService.FindShortUrlBySecretKey(r.Context(), v.Slug, v.Secret)
+			if code := suss.ErrorCode(err); code == suss.ENOTFOUND || code == suss.EUNAUTHORIZED {
+				continue
+			} else if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			stats, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			recent = append(recent, v)
+			items = append(items, html.HomepageShortURL{
+				Url:       shortUrl.ShortURL(s.PublicURL(r)),
+				ManageURL: fmt.Sprintf("/manage/%s", shortUrl.Slug),
+				ShortURL:  shortUrl,
+				Visits:    stats.Total,
+			})
+		}
+
+		if len(recent) != len(session.RecentShortURLs) {
+			session.RecentShortURLs = recent
+			if err := s.setSession(w, r, session); err != nil {
+				s.Error(w, r, err)
+				return
+			}
+		}
+
+		props := html.HomepageProps{
+			RecentShortURLs: items,
+			MailEnabled:     s.Mailer != nil,
+		}
+
+		// anonymous visitors solve a challenge in the background before
+		// creating a link
+		if s.ChallengeEnabled() && suss.UserFromContext(r.Context()) == nil {
+			var err error
+			if props.Challenge, props.ChallengeDifficulty, _, err = s.newChallenge(); err != nil {
+				s.Error(w, r, err)
+				return
+			}
+		}
+
+		html.Homepage(props).Render(r.Context(), w)
 	}
 }
 
 func (s *Server) handlerShortUrlCreate() http.HandlerFunc {
 	rateLimiter := httprate.NewRateLimiter(5, time.Minute, httprate.WithKeyByIP())
 	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		err := r.ParseForm()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err := r.ParseForm(); err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid form"))
 			return
 		}
 
 		url := r.Form.Get("url")
 		if url == "" {
-			http.Error(w, "url required", http.StatusBadRequest)
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "url required"))
+			return
+		}
+
+		if err := s.requireChallenge(r, r.Form.Get("challenge"), r.Form.Get("challenge_solution")); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		expiresAt, err := parseFormDateTime(r.Form.Get("expires_at"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid expiration date"))
+			return
+		}
+
+		maxClicks, err := parseFormInt(r.Form.Get("max_clicks"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid max clicks"))
 			return
 		}
 
 		shortUrl := &suss.ShortURL{
-			LongURL: url,
+			Slug:      r.Form.Get("slug"),
+			LongURL:   url,
+			ExpiresAt: expiresAt,
+			MaxClicks: maxClicks,
+			Password:  r.Form.Get("password"),
+			Email:     r.Form.Get("email"),
 		}
+
+		// links created while working in a workspace are shared with its members
+		if workspace, err := s.currentWorkspace(r); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if workspace != nil {
+			shortUrl.WorkspaceID = workspace.ID
+		}
+
 		if err := s.ShortURLService.Create(r.Context(), shortUrl); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.Error(w, r, err)
 			return
 		}
+		s.recordAnonymousCreation(r)
 
-		http.Redirect(w, r, fmt.Sprintf("/manage/%s?secret=%s", shortUrl.Slug, shortUrl.SecretKey), http.StatusSeeOther)
+		// remember the link so it is listed on the homepage
+		session := s.session(r)
+		session.AddRecentShortURL(shortUrl.Slug, shortUrl.SecretKey)
+		if err := s.setSession(w, r, session); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/manage/%s", shortUrl.Slug), http.StatusSeeOther)
 	}))
 
 	return handler.ServeHTTP
@@ -61,6 +156,33 @@ func (s *Server) handlerShortUrlPreview() http.HandlerFunc {
 			return
 		}
 
+		// links which no longer redirect do not reveal their destination
+		if shortUrl.IsDisabled() {
+			w.WriteHeader(http.StatusGone)
+			html.DisabledPage().Render(r.Context(), w)
+			return
+		}
+
+		if expired, err := s.isShortUrlExpired(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if expired {
+			w.WriteHeader(http.StatusGone)
+			html.ExpiredPage().Render(r.Context(), w)
+			return
+		}
+
+		if blocked, err := s.isShortUrlBlocked(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if blocked {
+			w.WriteHeader(http.StatusForbidden)
+			html.BlockedPage().Render(r.Context(), w)
+			return
+		}
+
+		// continuing goes through the short url, so every check of a visit
+		// applies and the click is counted
 		html.PreviewPage(html.PreviewPageProps{
 			Url:      shortUrl.ShortURL(s.PublicURL(r)),
 			ShortURL: shortUrl,
@@ -82,42 +204,513 @@ func (s *Server) handlerShortUrlVisit() http.HandlerFunc {
 			return
 		}
 
-		http.Redirect(w, r, shortUrl.LongURL, http.StatusSeeOther)
+		// disabled links never redirect, whatever else applies to them
+		if shortUrl.IsDisabled() {
+			w.WriteHeader(http.StatusGone)
+			html.DisabledPage().Render(r.Context(), w)
+			return
+		}
+
+		// refuse to redirect once the link has reached the end of its lifetime
+		if expired, err := s.isShortUrlExpired(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if expired {
+			w.WriteHeader(http.StatusGone)
+			html.ExpiredPage().Render(r.Context(), w)
+			return
+		}
+
+		// rules apply to existing links too, so newly blocked domains stop
+		// working straight away
+		if blocked, err := s.isShortUrlBlocked(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if blocked {
+			w.WriteHeader(http.StatusForbidden)
+			html.BlockedPage().Render(r.Context(), w)
+			return
+		}
+
+		// warn about flagged destinations instead of redirecting
+		if shortUrl.Threat != "" {
+			html.WarningPage(html.WarningPageProps{
+				ShortURL: shortUrl,
+			}).Render(r.Context(), w)
+			return
+		}
+
+		// ask for the password before revealing the destination
+		if shortUrl.HasPassword() {
+			html.PasswordPage(html.PasswordPageProps{
+				ShortURL: shortUrl,
+			}).Render(r.Context(), w)
+			return
+		}
+
+		s.redirectShortUrl(w, r, shortUrl)
 	}
 }
 
-func (s *Server) handlerShortUrlManage() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handlerShortUrlUnlock() http.HandlerFunc {
+	// throttle password attempts per slug so rotating ips does not help guessing
+	rateLimiter := httprate.NewRateLimiter(10, time.Minute, httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+		return chi.URLParam(r, "slug"), nil
+	}))
+	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		slug := chi.URLParam(r, "slug")
 		if slug == "" {
 			s.Error(w, r, suss.Errorf(suss.EINVALID, "slug required"))
 			return
 		}
 
-		secret := r.URL.Query().Get("secret")
-		if secret == "" {
-			s.Error(w, r, suss.Errorf(suss.EINVALID, "secret required"))
+		shortUrl, err := s.ShortURLService.FindDialBySlug(r.Context(), slug)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// disabled links never redirect, whatever else applies to them
+		if shortUrl.IsDisabled() {
+			w.WriteHeader(http.StatusGone)
+			html.DisabledPage().Render(r.Context(), w)
 			return
 		}
 
-		shortUrl, err := s.ShortURLService.FindDialBySlug(r.Context(), slug)
+		if expired, err := s.isShortUrlExpired(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if expired {
+			w.WriteHeader(http.StatusGone)
+			html.ExpiredPage().Render(r.Context(), w)
+			return
+		}
+
+		// rules apply to existing links too, so newly blocked domains stop
+		// working straight away
+		if blocked, err := s.isShortUrlBlocked(r, shortUrl); err != nil {
+			s.Error(w, r, err)
+			return
+		} else if blocked {
+			w.WriteHeader(http.StatusForbidden)
+			html.BlockedPage().Render(r.Context(), w)
+			return
+		}
+
+		// flagged links only redirect once the visitor chose to continue
+		if shortUrl.Threat != "" && r.PostFormValue(html.AcknowledgeFieldName) == "" {
+			html.WarningPage(html.WarningPageProps{
+				ShortURL: shortUrl,
+			}).Render(r.Context(), w)
+			return
+		}
+
+		if shortUrl.HasPassword() && !suss.ComparePassword(shortUrl.PasswordHash, r.PostFormValue("password")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			html.PasswordPage(html.PasswordPageProps{
+				ShortURL: shortUrl,
+				Invalid:  true,
+			}).Render(r.Context(), w)
+			return
+		}
+
+		s.redirectShortUrl(w, r, shortUrl)
+	}))
+
+	return handler.ServeHTTP
+}
+
+// isShortUrlExpired returns true if the short url should no longer redirect.
+// The maximum number of clicks is only enforced once the click is recorded,
+// see redirectShortUrl, this check saves asking for a password in vain.
+func (s *Server) isShortUrlExpired(r *http.Request, shortUrl *suss.ShortURL) (bool, error) {
+	var clicks int
+	if shortUrl.MaxClicks > 0 {
+		stats, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
 		if err != nil {
+			return false, err
+		}
+		clicks = stats.Total
+	}
+	return shortUrl.IsExpired(time.Now(), clicks), nil
+}
+
+// isShortUrlBlocked returns true if the domain rules no longer accept the
+// destination of the short url.
+func (s *Server) isShortUrlBlocked(r *http.Request, shortUrl *suss.ShortURL) (bool, error) {
+	rules, _, err := s.DomainRuleService.FindDomainRules(r.Context(), suss.DomainRuleFilter{})
+	if err != nil {
+		return false, err
+	}
+	return !suss.AllowsHost(rules, shortUrl.Host()), nil
+}
+
+// redirectShortUrl records a click and redirects the visitor to the destination.
+// Short urls with a maximum number of clicks only redirect if the click was
+// recorded within the maximum.
+func (s *Server) redirectShortUrl(w http.ResponseWriter, r *http.Request, shortUrl *suss.ShortURL) {
+	err := s.ClickService.Create(r.Context(), &suss.Click{
+		ShortURLID: shortUrl.ID,
+		Referrer:   r.Referer(),
+		UserAgent:  r.UserAgent(),
+		IPHash:     s.hash(s.RemoteIP(r)),
+	})
+	if suss.ErrorCode(err) == suss.ECONFLICT {
+		// other visits used up the last clicks since the link was looked up
+		w.WriteHeader(http.StatusGone)
+		html.ExpiredPage().Render(r.Context(), w)
+		return
+	} else if err != nil && shortUrl.MaxClicks > 0 {
+		s.Error(w, r, err)
+		return
+	} else if err != nil {
+		// a failure to record the visit should never block the redirect
+		log.Printf("cannot record click: slug=%s err=%s", shortUrl.Slug, err)
+	}
+
+	http.Redirect(w, r, shortUrl.LongURL, http.StatusSeeOther)
+}
+
+func (s *Server) handlerShortUrlManage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// exchange a secret sent in the query string, as in links shared before
+		// secrets were kept out of urls, and redirect to drop it from the url
+		if secret := r.URL.Query().Get("secret"); secret != "" {
+			s.unlockShortUrl(w, r, secret)
+			return
+		}
+
+		shortUrl, secret, err := s.findManagedShortUrl(r)
+		if suss.ErrorCode(err) == suss.EUNAUTHORIZED {
+			w.WriteHeader(http.StatusUnauthorized)
+			html.ManageUnlockPage(html.ManageUnlockPageProps{
+				Slug:        chi.URLParam(r, "slug"),
+				MailEnabled: s.Mailer != nil,
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil {
 			s.Error(w, r, err)
 			return
 		}
 
-		if secret != shortUrl.SecretKey {
-			s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid secret"))
+		stats, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		clicks, _, err := s.ClickService.FindClicks(r.Context(), suss.ClickFilter{
+			ShortURLID: &shortUrl.ID,
+			Limit:      10,
+		})
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// workspace viewers can see the link but not change it
+		role, err := s.shortUrlWorkspaceRole(r, shortUrl)
+		if err != nil {
+			s.Error(w, r, err)
 			return
 		}
+		readOnly := shortUrl.WorkspaceID != 0 && !suss.HasWorkspaceRole(role, suss.WorkspaceRoleEditor)
+
+		// owners managing without the secret only see the link once rotated
+		var manageURL string
+		if secret != "" {
+			manageURL = s.manageURL(r, shortUrl.Slug, secret)
+		}
 
 		html.ManagePage(html.ManagePageProps{
-			Url:      shortUrl.ShortURL(s.PublicURL(r)),
-			ShortURL: shortUrl,
+			Url:         shortUrl.ShortURL(s.PublicURL(r)),
+			ManageURL:   manageURL,
+			ShortURL:    shortUrl,
+			Stats:       stats,
+			Clicks:      clicks,
+			MailEnabled: s.Mailer != nil,
+			ReadOnly:    readOnly,
+		}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerShortUrlManageUnlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.unlockShortUrl(w, r, r.PostFormValue("secret"))
+	}
+}
+
+// unlockShortUrl verifies the secret for the "slug" route parameter and stores
+// it in the session so the manage page can be used without it in the url.
+func (s *Server) unlockShortUrl(w http.ResponseWriter, r *http.Request, secret string) {
+	slug := chi.URLParam(r, "slug")
+
+	shortUrl, err := s.ShortURLService.FindShortUrlBySecretKey(r.Context(), slug, secret)
+	if code := suss.ErrorCode(err); code == suss.EUNAUTHORIZED || code == suss.EINVALID {
+		w.WriteHeader(http.StatusUnauthorized)
+		html.ManageUnlockPage(html.ManageUnlockPageProps{
+			Slug:        slug,
+			Invalid:     true,
+			MailEnabled: s.Mailer != nil,
+		}).Render(r.Context(), w)
+		return
+	} else if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	session := s.session(r)
+	session.AddRecentShortURL(shortUrl.Slug, secret)
+	if err := s.setSession(w, r, session); err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/manage/%s", shortUrl.Slug), http.StatusSeeOther)
+}
+
+func (s *Server) handlerShortUrlUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortUrl, _, err := s.findManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		var upd suss.ShortURLUpdate
+		if v := r.PostFormValue("url"); v != "" {
+			upd.LongURL = &v
+		}
+		if _, ok := r.PostForm["expires_at"]; ok {
+			expiresAt, err := parseFormDateTime(r.PostForm.Get("expires_at"))
+			if err != nil {
+				s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid expiration date"))
+				return
+			}
+			upd.ExpiresAt = &expiresAt
+		}
+		if _, ok := r.PostForm["max_clicks"]; ok {
+			maxClicks, err := parseFormInt(r.PostForm.Get("max_clicks"))
+			if err != nil {
+				s.Error(w, r, suss.Errorf(suss.EINVALID, "invalid max clicks"))
+				return
+			}
+			upd.MaxClicks = &maxClicks
+		}
+		if _, ok := r.PostForm["password"]; ok {
+			password := r.PostForm.Get("password")
+			upd.Password = &password
+		}
+		if _, ok := r.PostForm["email"]; ok {
+			email := r.PostForm.Get("email")
+			upd.Email = &email
+		}
+
+		if _, err := s.ShortURLService.Update(r.Context(), shortUrl.ID, upd); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/manage/%s", shortUrl.Slug), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerShortUrlDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortUrl, _, err := s.findManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if err := s.ShortURLService.Delete(r.Context(), shortUrl.ID); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerShortUrlRotateSecret() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shortUrl, _, err := s.findManagedShortUrl(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if shortUrl, err = s.ShortURLService.RotateSecretKey(r.Context(), shortUrl.ID); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// keep managing the link with the new secret from this session
+		session := s.session(r)
+		session.AddRecentShortURL(shortUrl.Slug, shortUrl.SecretKey)
+		if err := s.setSession(w, r, session); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/manage/%s", shortUrl.Slug), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerShortUrlRecover() http.HandlerFunc {
+	// every recovery sends an email so throttle them per slug as well as per
+	// ip, to limit how often a known email can be sent recovery links
+	slugRateLimiter := httprate.NewRateLimiter(3, time.Hour, httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+		return chi.URLParam(r, "slug"), nil
+	}))
+	ipRateLimiter := httprate.NewRateLimiter(5, time.Hour, httprate.WithKeyByIP())
+	handler := ipRateLimiter.Handler(slugRateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Mailer == nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTIMPLEMENTED, "Recovery by email is not available."))
+			return
+		}
+
+		// send a recovery link only if the email matches, without telling the
+		// visitor whether it did
+		slug := chi.URLParam(r, "slug")
+		shortUrl, token, err := s.ShortURLService.CreateRecoveryToken(r.Context(), slug, r.PostFormValue("email"))
+		if err == nil {
+			err = s.sendRecoveryLink(r, shortUrl, token)
+		}
+		if err != nil && suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			s.Error(w, r, err)
+			return
+		}
+
+		html.ManageUnlockPage(html.ManageUnlockPageProps{
+			Slug:         slug,
+			MailEnabled:  true,
+			RecoverySent: true,
+		}).Render(r.Context(), w)
+	})))
+
+	return handler.ServeHTTP
+}
+
+// sendRecoveryLink emails the owner of shortUrl a link to redeem the recovery
+// token for a new manage link. Links are built from the configured base url
+// rather than the request, so they cannot be pointed elsewhere by its headers.
+func (s *Server) sendRecoveryLink(r *http.Request, shortUrl *suss.ShortURL, token string) error {
+	return s.Mailer.SendMail(r.Context(), &suss.Mail{
+		To:      shortUrl.Email,
+		Subject: fmt.Sprintf("Recover your manage link for %s", shortUrl.ShortURL(s.BaseURL)),
+		Body: fmt.Sprintf("Someone asked for a new manage link for %s.\n\n"+
+			"Open this link within an hour to get a new manage link:\n%s/manage/%s/redeem#token=%s\n\n"+
+			"Your current manage link keeps working until then. If you did not ask for this, you can ignore this email.\n",
+			shortUrl.ShortURL(s.BaseURL), s.BaseURL, shortUrl.Slug, url.QueryEscape(token)),
+	})
+}
+
+func (s *Server) handlerShortUrlRedeem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		html.ManageRedeemPage(html.ManageRedeemPageProps{
+			Slug: chi.URLParam(r, "slug"),
 		}).Render(r.Context(), w)
 	}
 }
 
+func (s *Server) handlerShortUrlRedeemSubmit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slug := chi.URLParam(r, "slug")
+
+		// redeeming the token rotates the secret, which is kept in the session
+		shortUrl, err := s.ShortURLService.RecoverShortUrl(r.Context(), slug, r.PostFormValue("token"))
+		if suss.ErrorCode(err) == suss.EUNAUTHORIZED {
+			w.WriteHeader(http.StatusUnauthorized)
+			html.ManageRedeemPage(html.ManageRedeemPageProps{
+				Slug:    slug,
+				Invalid: true,
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		session := s.session(r)
+		session.AddRecentShortURL(shortUrl.Slug, shortUrl.SecretKey)
+		if err := s.setSession(w, r, session); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/manage/%s", shortUrl.Slug), http.StatusSeeOther)
+	}
+}
+
+// manageURL returns the private manage link of a short url. The secret is
+// passed in the fragment so it is never sent to the server or logged.
+func (s *Server) manageURL(r *http.Request, slug, secret string) string {
+	return fmt.Sprintf("%s/manage/%s#secret=%s", s.PublicURL(r), slug, url.QueryEscape(secret))
+}
+
+// parseFormDateTime parses the value of a "datetime-local" input as UTC.
+// An empty value returns the zero time.
+func parseFormDateTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(html.DateTimeLocalLayout, v, time.UTC)
+}
+
+// parseFormInt parses the value of a "number" input. An empty value returns zero.
+func parseFormInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// findManagedShortUrl looks up the short url for the "slug" route parameter
+// and verifies the secret stored for it in the session. Short urls owned by
+// the signed in user, or in one of their workspaces, are returned without a
+// secret. Changes to workspace short urls are further checked by role.
+func (s *Server) findManagedShortUrl(r *http.Request) (*suss.ShortURL, string, error) {
+	slug := chi.URLParam(r, "slug")
+	session := s.session(r)
+	if secret, ok := session.ShortURLSecret(slug); ok {
+		shortUrl, err := s.ShortURLService.FindShortUrlBySecretKey(r.Context(), slug, secret)
+		if err == nil {
+			return shortUrl, secret, nil
+		} else if suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			return nil, "", err
+		}
+	}
+
+	shortUrl, err := s.findUserShortUrl(r, slug)
+	if err != nil {
+		return nil, "", err
+	}
+	return shortUrl, "", nil
+}
+
+// findUserShortUrl returns the short url with slug if the signed in user owns
+// it or is a member of its workspace. Editing is further restricted by the
+// role checks of ShortURLService.
+func (s *Server) findUserShortUrl(r *http.Request, slug string) (*suss.ShortURL, error) {
+	if user := suss.UserFromContext(r.Context()); user != nil {
+		shortUrl, err := s.ShortURLService.FindDialBySlug(r.Context(), slug)
+		if err != nil {
+			return nil, err
+		} else if shortUrl.IsOwnedBy(user) {
+			return shortUrl, nil
+		}
+
+		if role, err := s.shortUrlWorkspaceRole(r, shortUrl); err != nil {
+			return nil, err
+		} else if role != "" {
+			return shortUrl, nil
+		}
+	}
+
+	return nil, suss.Errorf(suss.EUNAUTHORIZED, "secret required")
+}
+
 func (s *Server) handlerShortUrlQrCode() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		slug := chi.URLParam(r, "slug")
@@ -0,0 +1,538 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+func TestServer_ShortUrlRecover(t *testing.T) {
+	const baseURL = "https://sus.example"
+
+	// MustCreateShortUrl creates a link recoverable by owner@example.com.
+	MustCreateShortUrl := func(tb testing.TB, s *TestServer) *suss.ShortURL {
+		tb.Helper()
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com", Email: "owner@example.com"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			tb.Fatal(err)
+		}
+		return shortUrl
+	}
+
+	tokenRegexp := regexp.MustCompile(`#token=(\S+)`)
+
+	t.Run("OK", func(t *testing.T) {
+		mailer := &Mailer{}
+		s := MustOpenServer(t, func(s *TestServer) {
+			s.Mailer, s.BaseURL = mailer, baseURL
+		})
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		// the emailed link must not be built from headers anyone can send
+		form := url.Values{"email": {"Owner@Example.com"}}
+		form.Set(html.CSRFFieldName, c.CSRFToken())
+		req, _ := http.NewRequest(http.MethodPost, s.URL()+"/manage/"+shortUrl.Slug+"/recover", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Forwarded-Host", "evil.example")
+		if resp, _ := c.Do(req); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		mails := mailer.Mails()
+		if len(mails) != 1 {
+			t.Fatalf("len(mails)=%d", len(mails))
+		} else if got, want := mails[0].To, "owner@example.com"; got != want {
+			t.Fatalf("To=%q, want %q", got, want)
+		} else if strings.Contains(mails[0].Body, "evil.example") {
+			t.Fatalf("Body uses request host: %s", mails[0].Body)
+		} else if !strings.Contains(mails[0].Body, baseURL+"/manage/"+shortUrl.Slug+"/redeem#token=") {
+			t.Fatalf("Body has no recovery link: %s", mails[0].Body)
+		}
+
+		// the secret is only rotated once the token is redeemed
+		if _, err := s.ShortURLService.FindShortUrlBySecretKey(context.Background(), shortUrl.Slug, shortUrl.SecretKey); err != nil {
+			t.Fatalf("secret rotated before redeeming: %s", err)
+		}
+
+		m := tokenRegexp.FindStringSubmatch(mails[0].Body)
+		if m == nil {
+			t.Fatal("token not found")
+		}
+		token, err := url.QueryUnescape(m[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, _ := c.PostForm("/manage/"+shortUrl.Slug+"/redeem", url.Values{"token": {token}})
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), "/manage/"+shortUrl.Slug; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+
+		if _, err := s.ShortURLService.FindShortUrlBySecretKey(context.Background(), shortUrl.Slug, shortUrl.SecretKey); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("old secret still works: err=%v", err)
+		}
+
+		// the new secret is kept in the session
+		if resp, _ := c.Get("/manage/" + shortUrl.Slug); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// tokens can only be redeemed once
+		if resp, _ := c.PostForm("/manage/"+shortUrl.Slug+"/redeem", url.Values{"token": {token}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrEmailMismatch", func(t *testing.T) {
+		mailer := &Mailer{}
+		s := MustOpenServer(t, func(s *TestServer) {
+			s.Mailer, s.BaseURL = mailer, baseURL
+		})
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		// the response does not tell whether the email matched
+		if resp, _ := c.PostForm("/manage/"+shortUrl.Slug+"/recover", url.Values{"email": {"other@example.com"}}); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if n := len(mailer.Mails()); n != 0 {
+			t.Fatalf("len(mails)=%d", n)
+		}
+	})
+
+	t.Run("ErrSendMail", func(t *testing.T) {
+		mailer := &Mailer{Err: errors.New("smtp unavailable")}
+		s := MustOpenServer(t, func(s *TestServer) {
+			s.Mailer, s.BaseURL = mailer, baseURL
+		})
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		if resp, _ := c.PostForm("/manage/"+shortUrl.Slug+"/recover", url.Values{"email": {"owner@example.com"}}); resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// a failure to send must not lock the owner out
+		if _, err := s.ShortURLService.FindShortUrlBySecretKey(context.Background(), shortUrl.Slug, shortUrl.SecretKey); err != nil {
+			t.Fatalf("secret rotated: %s", err)
+		}
+	})
+
+	t.Run("ErrInvalidToken", func(t *testing.T) {
+		s := MustOpenServer(t, func(s *TestServer) {
+			s.Mailer, s.BaseURL = &Mailer{}, baseURL
+		})
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		if resp, _ := c.PostForm("/manage/"+shortUrl.Slug+"/redeem", url.Values{"token": {"bogus"}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if _, err := s.ShortURLService.FindShortUrlBySecretKey(context.Background(), shortUrl.Slug, shortUrl.SecretKey); err != nil {
+			t.Fatalf("secret rotated: %s", err)
+		}
+	})
+}
+
+// URLChecker flags urls containing "evil" as malware.
+type URLChecker struct{}
+
+func (URLChecker) CheckURL(ctx context.Context, url string) (string, error) {
+	if strings.Contains(url, "evil") {
+		return "MALWARE", nil
+	}
+	return "", nil
+}
+
+func TestServer_ShortUrlUnlock(t *testing.T) {
+	t.Run("Flagged", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://evil.example/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		// the url was listed after the link was created
+		s.DB.URLChecker = URLChecker{}
+		if _, err := s.DB.CheckShortURLs(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		c := s.NewClient(t)
+
+		// posting the link directly still shows the warning
+		if resp, body := c.PostForm("/"+shortUrl.Slug, url.Values{}); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "may be unsafe") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+
+		resp, _ := c.PostForm("/"+shortUrl.Slug, url.Values{html.AcknowledgeFieldName: {"1"}})
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), shortUrl.LongURL; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+
+		// only the visit past the warning is counted
+		if stats, err := s.ClickService.FindClickStats(context.Background(), shortUrl.ID); err != nil {
+			t.Fatal(err)
+		} else if stats.Total != 1 {
+			t.Fatalf("Total=%d", stats.Total)
+		}
+	})
+}
+
+func TestServer_ShortUrlVisit(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp, _ := s.NewClient(t).Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), "https://example.com/"; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("Blocked", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://login.phish.example/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		// links to newly blocked domains stop working immediately
+		rule := &suss.DomainRule{Pattern: "*.phish.example", Action: suss.DomainRuleBlock}
+		if err := s.DomainRuleService.Create(adminContext(), rule); err != nil {
+			t.Fatal(err)
+		}
+		c := s.NewClient(t)
+		if resp, _ := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		if err := s.DomainRuleService.Delete(adminContext(), rule.ID); err != nil {
+			t.Fatal(err)
+		}
+		if resp, _ := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("NotAllowed", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://example.org/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		} else if err := s.DomainRuleService.Create(adminContext(), &suss.DomainRule{Pattern: "example.com", Action: suss.DomainRuleAllow}); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp, _ := s.NewClient(t).Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_ShortUrlPreview(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/page"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		// continuing goes through the short url rather than straight to the
+		// destination
+		if resp, body := s.NewClient(t).Get("/" + shortUrl.Slug + "+"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, `href="`+s.URL()+"/"+shortUrl.Slug+`"`) {
+			t.Fatalf("no link to the short url: %s", body)
+		} else if strings.Contains(body, `href="`+shortUrl.LongURL+`"`) {
+			t.Fatalf("links to the destination: %s", body)
+		}
+	})
+
+	t.Run("Blocked", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://phish.example/login"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		} else if err := s.DomainRuleService.Create(adminContext(), &suss.DomainRule{Pattern: "phish.example", Action: suss.DomainRuleBlock}); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp, body := s.NewClient(t).Get("/preview/" + shortUrl.Slug); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if strings.Contains(body, "phish.example") {
+			t.Fatalf("destination revealed: %s", body)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/secret-page", MaxClicks: 1}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		c := s.NewClient(t)
+		if resp, _ := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, body := c.Get("/" + shortUrl.Slug + "+"); resp.StatusCode != http.StatusGone {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if strings.Contains(body, "secret-page") {
+			t.Fatalf("destination revealed: %s", body)
+		}
+	})
+}
+
+// MustShorten creates a link from the homepage form and returns its slug. The
+// client keeps the secret needed to manage it in its session.
+func (c *Client) MustShorten(form url.Values) string {
+	c.tb.Helper()
+	resp, body := c.PostForm("/shorten", form)
+	if resp.StatusCode != http.StatusSeeOther {
+		c.tb.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+	}
+	slug, ok := strings.CutPrefix(resp.Header.Get("Location"), "/manage/")
+	if !ok {
+		c.tb.Fatalf("unexpected Location: %s", resp.Header.Get("Location"))
+	}
+	return slug
+}
+
+func TestServer_Homepage(t *testing.T) {
+	t.Run("RecentShortURLs", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		slug := c.MustShorten(url.Values{"url": {"https://example.com/recent"}})
+
+		if _, body := c.Get("/"); !strings.Contains(body, `href="/manage/`+slug+`"`) || !strings.Contains(body, "https://example.com/recent") {
+			t.Fatalf("short url not listed: %s", body)
+		}
+
+		// other visitors do not see the link
+		if _, body := s.NewClient(t).Get("/"); strings.Contains(body, slug) {
+			t.Fatal("short url listed for another visitor")
+		}
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		slug := c.MustShorten(url.Values{"url": {"https://example.com/recent"}})
+
+		shortUrl, err := s.ShortURLService.FindDialBySlug(context.Background(), slug)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := s.ShortURLService.Delete(adminContext(), shortUrl.ID); err != nil {
+			t.Fatal(err)
+		}
+		if resp, body := c.Get("/"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if strings.Contains(body, slug) {
+			t.Fatal("deleted short url listed")
+		}
+	})
+}
+
+func TestServer_ShortUrlCreate(t *testing.T) {
+	t.Run("ErrOwnHost", func(t *testing.T) {
+		// links back to the shortener would redirect forever
+		s := MustOpenServer(t, nil)
+		if resp, _ := s.NewClient(t).PostForm("/shorten", url.Values{"url": {s.URL() + "/abc"}}); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrScheme", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		if resp, body := s.NewClient(t).PostForm("/shorten", url.Values{"url": {"javascript:alert(1)"}}); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "schemes: http, https") {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	})
+}
+
+func TestServer_ShortUrlManage(t *testing.T) {
+	t.Run("Update", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		slug := c.MustShorten(url.Values{"url": {"https://example.com/"}})
+
+		if resp, _ := c.Get("/manage/" + slug); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// forms override the method with "_method"
+		if resp, body := c.PostForm("/manage/"+slug, url.Values{"_method": {http.MethodPatch}, "url": {"https://example.com/new"}}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+		}
+		if shortUrl, err := s.ShortURLService.FindDialBySlug(context.Background(), slug); err != nil {
+			t.Fatal(err)
+		} else if got, want := shortUrl.LongURL, "https://example.com/new"; got != want {
+			t.Fatalf("LongURL=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		slug := c.MustShorten(url.Values{"url": {"https://example.com/"}})
+
+		if resp, _ := c.PostForm("/manage/"+slug, url.Values{"_method": {http.MethodDelete}}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if _, err := s.ShortURLService.FindDialBySlug(context.Background(), slug); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp, _ := c.Get("/" + slug); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		slug := s.NewClient(t).MustShorten(url.Values{"url": {"https://example.com/"}})
+
+		// another visitor has no secret for the link
+		c := s.NewClient(t)
+		if resp, _ := c.Get("/manage/" + slug); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, _ := c.PostForm("/manage/"+slug, url.Values{"_method": {http.MethodDelete}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if _, err := s.ShortURLService.FindDialBySlug(context.Background(), slug); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("SecretQuery", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := &suss.ShortURL{Slug: "shared", LongURL: "https://example.com/"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			t.Fatal(err)
+		}
+
+		// secrets in the query string of older links are exchanged for the
+		// session and dropped from the url
+		c := s.NewClient(t)
+		resp, _ := c.Get("/manage/shared?secret=" + url.QueryEscape(shortUrl.SecretKey))
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), "/manage/shared"; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+		if resp, _ := c.Follow(resp); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("ErrSecretQuery", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		slug := s.NewClient(t).MustShorten(url.Values{"url": {"https://example.com/"}})
+		if resp, _ := s.NewClient(t).Get("/manage/" + slug + "?secret=bogus"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_ReservedSlugs(t *testing.T) {
+	s := MustOpenServer(t, nil)
+
+	// a link with the slug of a route would never be reachable
+	if err := chi.Walk(s.router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, "{") && !suss.IsReservedSlug(segment) {
+			t.Errorf("%s %s: %q is not a reserved slug", method, route, segment)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_ShortUrlUnlockPassword(t *testing.T) {
+	// MustCreateShortUrl creates a link protected by a password.
+	MustCreateShortUrl := func(tb testing.TB, s *TestServer) *suss.ShortURL {
+		tb.Helper()
+		shortUrl := &suss.ShortURL{LongURL: "https://example.com/private", Password: "hunter22"}
+		if err := s.ShortURLService.Create(context.Background(), shortUrl); err != nil {
+			tb.Fatal(err)
+		}
+		return shortUrl
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		// the destination is not revealed before the password is entered
+		if resp, body := c.Get("/" + shortUrl.Slug); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if strings.Contains(body, "example.com/private") {
+			t.Fatalf("destination revealed: %s", body)
+		}
+
+		resp, _ := c.PostForm("/"+shortUrl.Slug, url.Values{"password": {"hunter22"}})
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), shortUrl.LongURL; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidPassword", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := MustCreateShortUrl(t, s)
+		c := s.NewClient(t)
+
+		if resp, body := c.PostForm("/"+shortUrl.Slug, url.Values{"password": {"wrong"}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "The password is incorrect.") {
+			t.Fatalf("unexpected body: %s", body)
+		} else if strings.Contains(body, "example.com/private") {
+			t.Fatalf("destination revealed: %s", body)
+		}
+
+		// failed attempts are not counted as visits
+		if stats, err := s.ClickService.FindClickStats(context.Background(), shortUrl.ID); err != nil {
+			t.Fatal(err)
+		} else if stats.Total != 0 {
+			t.Fatalf("Total=%d", stats.Total)
+		}
+	})
+
+	t.Run("ErrRateLimited", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		shortUrl := MustCreateShortUrl(t, s)
+
+		// guesses are limited per link, whoever makes them
+		for i := 0; i < 10; i++ {
+			if resp, _ := s.NewClient(t).PostForm("/"+shortUrl.Slug, url.Values{"password": {"wrong"}}); resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("StatusCode=%d", resp.StatusCode)
+			}
+		}
+		if resp, _ := s.NewClient(t).PostForm("/"+shortUrl.Slug, url.Values{"password": {"hunter22"}}); resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
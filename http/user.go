@@ -0,0 +1,209 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/httprate"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+// UserShortURLsPageSize is the number of links listed per page on /links.
+const UserShortURLsPageSize = 20
+
+func (s *Server) handlerLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		html.LoginPage(html.LoginPageProps{
+			Next:        r.URL.Query().Get("next"),
+			OIDCEnabled: s.OIDCEnabled(),
+		}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerLoginSubmit() http.HandlerFunc {
+	rateLimiter := httprate.NewRateLimiter(10, time.Minute, httprate.WithKeyByIP())
+	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, next := r.PostFormValue("email"), r.PostFormValue("next")
+
+		user, err := s.UserService.Authenticate(r.Context(), email, r.PostFormValue("password"))
+		if suss.ErrorCode(err) == suss.EUNAUTHORIZED {
+			w.WriteHeader(http.StatusUnauthorized)
+			html.LoginPage(html.LoginPageProps{
+				Email:       email,
+				Next:        next,
+				Error:       suss.ErrorMessage(err),
+				OIDCEnabled: s.OIDCEnabled(),
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if err := s.login(w, r, user); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, localRedirect(next), http.StatusSeeOther)
+	}))
+
+	return handler.ServeHTTP
+}
+
+func (s *Server) handlerRegister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		html.RegisterPage(html.RegisterPageProps{}).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerRegisterSubmit() http.HandlerFunc {
+	rateLimiter := httprate.NewRateLimiter(5, time.Minute, httprate.WithKeyByIP())
+	handler := rateLimiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := &suss.User{
+			Name:     strings.TrimSpace(r.PostFormValue("name")),
+			Email:    r.PostFormValue("email"),
+			Password: r.PostFormValue("password"),
+		}
+		if user.Password == "" {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Password required."))
+			return
+		}
+
+		if err := s.UserService.Create(r.Context(), user); suss.ErrorCode(err) == suss.EINVALID || suss.ErrorCode(err) == suss.ECONFLICT {
+			w.WriteHeader(ErrorStatusCode(suss.ErrorCode(err)))
+			html.RegisterPage(html.RegisterPageProps{
+				Name:  user.Name,
+				Email: user.Email,
+				Error: suss.ErrorMessage(err),
+			}).Render(r.Context(), w)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		if err := s.login(w, r, user); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}))
+
+	return handler.ServeHTTP
+}
+
+func (s *Server) handlerLogout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie := s.session(r)
+		if cookie.Token != "" {
+			session, err := s.SessionService.FindSessionByToken(r.Context(), cookie.Token)
+			if err == nil {
+				err = s.SessionService.Delete(r.Context(), session.ID)
+			}
+			if err != nil && suss.ErrorCode(err) != suss.ENOTFOUND {
+				s.Error(w, r, err)
+				return
+			}
+		}
+
+		cookie.Token, cookie.WorkspaceID, cookie.AdminTokenHash = "", 0, ""
+		if err := s.setSession(w, r, cookie); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerUserShortUrls() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := suss.UserFromContext(r.Context())
+		if user == nil {
+			http.Redirect(w, r, "/login?next=/links", http.StatusSeeOther)
+			return
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if offset < 0 {
+			offset = 0
+		}
+
+		// list the links of the current workspace, or the personal links of
+		// the user outside of any workspace
+		workspace, err := s.currentWorkspace(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		filter := suss.ShortURLFilter{
+			Offset: offset,
+			Limit:  UserShortURLsPageSize,
+		}
+		if workspace != nil {
+			filter.WorkspaceID = &workspace.ID
+		} else {
+			noWorkspace := 0
+			filter.OwnerID, filter.WorkspaceID = &user.ID, &noWorkspace
+		}
+
+		shortUrls, n, err := s.ShortURLService.FindShortUrls(r.Context(), filter)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		items := make([]html.HomepageShortURL, len(shortUrls))
+		for i, shortUrl := range shortUrls {
+			stats, err := s.ClickService.FindClickStats(r.Context(), shortUrl.ID)
+			if err != nil {
+				s.Error(w, r, err)
+				return
+			}
+
+			items[i] = html.HomepageShortURL{
+				Url:       shortUrl.ShortURL(s.PublicURL(r)),
+				ManageURL: fmt.Sprintf("/manage/%s", shortUrl.Slug),
+				ShortURL:  shortUrl,
+				Visits:    stats.Total,
+			}
+		}
+
+		html.UserShortURLsPage(html.UserShortURLsPageProps{
+			Workspace: workspace,
+			ShortURLs: items,
+			N:         n,
+			Offset:    offset,
+			Limit:     UserShortURLsPageSize,
+		}).Render(r.Context(), w)
+	}
+}
+
+// login starts a new session for user and stores its token in the cookie.
+func (s *Server) login(w http.ResponseWriter, r *http.Request, user *suss.User) error {
+	session := &suss.Session{UserID: user.ID}
+	if err := s.SessionService.Create(r.Context(), session); err != nil {
+		return err
+	}
+
+	// signing in also ends any single sign-on in progress
+	cookie := s.session(r)
+	cookie.Token, cookie.OIDC = session.Token, nil
+	return s.setSession(w, r, cookie)
+}
+
+// localRedirect returns next if it is a path on this site, or "/" otherwise,
+// so the login form cannot be used to redirect to another site.
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// MustRegister registers a new account and signs the client in.
+func (c *Client) MustRegister(name, email, password string) {
+	c.tb.Helper()
+	resp, body := c.PostForm("/register", url.Values{"name": {name}, "email": {email}, "password": {password}})
+	if resp.StatusCode != http.StatusSeeOther {
+		c.tb.Fatalf("StatusCode=%d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestServer_Register(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		c.MustRegister("Susy", "susy@example.com", "password123")
+
+		// links created while signed in belong to the user, without a secret
+		slug := c.MustShorten(url.Values{"url": {"https://example.com/owned"}})
+		if resp, body := c.Get("/links"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, "/manage/"+slug) {
+			t.Fatalf("short url not listed: %s", body)
+		}
+	})
+
+	t.Run("ErrEmailTaken", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		s.NewClient(t).MustRegister("Susy", "susy@example.com", "password123")
+
+		resp, body := s.NewClient(t).PostForm("/register", url.Values{"name": {"Susy"}, "email": {"susy@example.com"}, "password": {"password123"}})
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if !strings.Contains(body, `value="susy@example.com"`) {
+			t.Fatalf("form not filled in again: %s", body)
+		}
+	})
+}
+
+func TestServer_Login(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		s.NewClient(t).MustRegister("Susy", "susy@example.com", "password123")
+
+		c := s.NewClient(t)
+		if resp, _ := c.Get("/links"); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		resp, _ := c.PostForm("/login", url.Values{"email": {"susy@example.com"}, "password": {"password123"}, "next": {"/links"}})
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		} else if got, want := resp.Header.Get("Location"), "/links"; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+		if resp, _ := c.Follow(resp); resp.StatusCode != http.StatusOK {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+
+		// signing out ends the session
+		if resp, _ := c.PostForm("/logout", url.Values{}); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, _ := c.Get("/links"); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+
+	t.Run("OpenRedirect", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		c := s.NewClient(t)
+		c.MustRegister("Susy", "susy@example.com", "password123")
+
+		resp, _ := c.PostForm("/login", url.Values{"email": {"susy@example.com"}, "password": {"password123"}, "next": {"//evil.example"}})
+		if got, want := resp.Header.Get("Location"), "/"; got != want {
+			t.Fatalf("Location=%q, want %q", got, want)
+		}
+	})
+
+	t.Run("ErrInvalidPassword", func(t *testing.T) {
+		s := MustOpenServer(t, nil)
+		s.NewClient(t).MustRegister("Susy", "susy@example.com", "password123")
+
+		c := s.NewClient(t)
+		if resp, _ := c.PostForm("/login", url.Values{"email": {"susy@example.com"}, "password": {"password124"}}); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+		if resp, _ := c.Get("/links"); resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode=%d", resp.StatusCode)
+		}
+	})
+}
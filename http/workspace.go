@@ -0,0 +1,251 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heyjorgedev/suss"
+	"github.com/heyjorgedev/suss/http/html"
+)
+
+func (s *Server) handlerWorkspaces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if suss.UserFromContext(r.Context()) == nil {
+			http.Redirect(w, r, "/login?next=/workspaces", http.StatusSeeOther)
+			return
+		}
+
+		workspaces, _, err := s.WorkspaceService.FindWorkspaces(r.Context(), suss.WorkspaceFilter{})
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		current, err := s.currentWorkspace(r)
+		if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		props := html.WorkspacesPageProps{Workspaces: workspaces}
+		if current != nil {
+			props.CurrentID = current.ID
+		}
+		html.WorkspacesPage(props).Render(r.Context(), w)
+	}
+}
+
+func (s *Server) handlerWorkspaceCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		workspace := &suss.Workspace{Name: strings.TrimSpace(r.PostFormValue("name"))}
+		if err := s.WorkspaceService.Create(r.Context(), workspace); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		// start working in the new workspace straight away
+		if err := s.setCurrentWorkspace(w, r, workspace.ID); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", workspace.ID), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerWorkspaceSwitch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PostFormValue("workspace_id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.EINVALID, "Invalid workspace."))
+			return
+		}
+
+		// zero switches back to personal links, anything else must be a
+		// workspace the user is a member of
+		if id != 0 {
+			if _, err := s.WorkspaceService.FindWorkspaceByID(r.Context(), id); err != nil {
+				s.Error(w, r, err)
+				return
+			}
+		}
+
+		if err := s.setCurrentWorkspace(w, r, id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/links", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerWorkspace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.renderWorkspacePage(w, r, nil)
+	}
+}
+
+func (s *Server) handlerWorkspaceDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Workspace not found."))
+			return
+		}
+
+		if err := s.WorkspaceService.Delete(r.Context(), id); err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, "/workspaces", http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerWorkspaceMemberInvite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Workspace not found."))
+			return
+		}
+
+		if _, err := s.WorkspaceService.InviteWorkspaceMember(r.Context(), id, r.PostFormValue("email"), r.PostFormValue("role")); err != nil {
+			s.renderWorkspacePage(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", id), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerWorkspaceMemberUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		memberID, err := strconv.Atoi(chi.URLParam(r, "memberID"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Workspace member not found."))
+			return
+		}
+
+		if _, err := s.WorkspaceService.UpdateWorkspaceMember(r.Context(), memberID, r.PostFormValue("role")); err != nil {
+			s.renderWorkspacePage(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/workspaces/%s", chi.URLParam(r, "id")), http.StatusSeeOther)
+	}
+}
+
+func (s *Server) handlerWorkspaceMemberRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		memberID, err := strconv.Atoi(chi.URLParam(r, "memberID"))
+		if err != nil {
+			s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Workspace member not found."))
+			return
+		}
+
+		if err := s.WorkspaceService.RemoveWorkspaceMember(r.Context(), memberID); err != nil {
+			s.renderWorkspacePage(w, r, err)
+			return
+		}
+
+		// members leaving a workspace can no longer see it
+		id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+		if _, err := s.WorkspaceService.FindWorkspaceByID(r.Context(), id); suss.ErrorCode(err) == suss.ENOTFOUND {
+			http.Redirect(w, r, "/workspaces", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			s.Error(w, r, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/workspaces/%d", id), http.StatusSeeOther)
+	}
+}
+
+// renderWorkspacePage renders the members of the "id" workspace. Validation
+// and permission errors from a submitted form are shown on the page.
+func (s *Server) renderWorkspacePage(w http.ResponseWriter, r *http.Request, formErr error) {
+	if suss.UserFromContext(r.Context()) == nil {
+		http.Redirect(w, r, "/login?next="+r.URL.Path, http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		s.Error(w, r, suss.Errorf(suss.ENOTFOUND, "Workspace not found."))
+		return
+	}
+
+	workspace, err := s.WorkspaceService.FindWorkspaceByID(r.Context(), id)
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	members, err := s.WorkspaceService.FindWorkspaceMembers(r.Context(), id)
+	if err != nil {
+		s.Error(w, r, err)
+		return
+	}
+
+	props := html.WorkspacePageProps{
+		Workspace: workspace,
+		Members:   members,
+		UserID:    suss.UserIDFromContext(r.Context()),
+	}
+	if formErr != nil {
+		switch suss.ErrorCode(formErr) {
+		case suss.EINVALID, suss.ENOTFOUND, suss.ECONFLICT, suss.EUNAUTHORIZED:
+			w.WriteHeader(requestStatusCode(r, suss.ErrorCode(formErr)))
+			props.Error = suss.ErrorMessage(formErr)
+		default:
+			s.Error(w, r, formErr)
+			return
+		}
+	}
+	html.WorkspacePage(props).Render(r.Context(), w)
+}
+
+// currentWorkspace returns the workspace selected in the session, or nil when
+// working on personal links. A workspace the user has since left is ignored.
+func (s *Server) currentWorkspace(r *http.Request) (*suss.Workspace, error) {
+	id := s.session(r).WorkspaceID
+	if id == 0 || suss.UserFromContext(r.Context()) == nil {
+		return nil, nil
+	}
+
+	workspace, err := s.WorkspaceService.FindWorkspaceByID(r.Context(), id)
+	if suss.ErrorCode(err) == suss.ENOTFOUND {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+// setCurrentWorkspace stores the workspace the user works in in the session.
+func (s *Server) setCurrentWorkspace(w http.ResponseWriter, r *http.Request, id int) error {
+	session := s.session(r)
+	session.WorkspaceID = id
+	return s.setSession(w, r, session)
+}
+
+// shortUrlWorkspaceRole returns the role of the signed in user in the
+// workspace of shortUrl, or an empty string if they are not a member.
+func (s *Server) shortUrlWorkspaceRole(r *http.Request, shortUrl *suss.ShortURL) (string, error) {
+	if shortUrl.WorkspaceID == 0 || suss.UserFromContext(r.Context()) == nil {
+		return "", nil
+	}
+
+	workspace, err := s.WorkspaceService.FindWorkspaceByID(r.Context(), shortUrl.WorkspaceID)
+	if suss.ErrorCode(err) == suss.ENOTFOUND {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return workspace.Role, nil
+}
@@ -0,0 +1,41 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// Identity links a user to their account at an external identity provider,
+// such as an OpenID Connect issuer, so they can sign in without a password.
+type Identity struct {
+	ID     int   `json:"id"`
+	UserID int   `json:"user_id"`
+	User   *User `json:"user"`
+
+	// Issuer & subject uniquely identify the account at the provider.
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (i *Identity) Validate() error {
+	if i.UserID == 0 {
+		return Errorf(EINVALID, "User required.")
+	} else if i.Issuer == "" {
+		return Errorf(EINVALID, "Issuer required.")
+	} else if i.Subject == "" {
+		return Errorf(EINVALID, "Subject required.")
+	}
+	return nil
+}
+
+type IdentityService interface {
+	// CreateIdentity signs a user in through an external identity. A known
+	// issuer & subject returns the existing identity and its user. Otherwise
+	// the identity is linked to the current signed in user, or a new user is
+	// provisioned from identity.User on first login. Returns an ECONFLICT error
+	// if another user has the email of identity.User, which must sign in and
+	// link the identity themselves.
+	CreateIdentity(ctx context.Context, identity *Identity) error
+}
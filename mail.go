@@ -0,0 +1,29 @@
+package suss
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+)
+
+// Mail represents a plain text email sent by the application.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails, such as the manage link of a short url.
+type Mailer interface {
+	SendMail(ctx context.Context, m *Mail) error
+}
+
+// NormalizeEmail returns the bare, lowercase address of email or an EINVALID
+// error if it is not a valid email address.
+func NormalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" {
+		return "", Errorf(EINVALID, "Invalid email address.")
+	}
+	return strings.ToLower(addr.Address), nil
+}
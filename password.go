@@ -0,0 +1,39 @@
+package suss
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the minimum length of an account password.
+const MinPasswordLength = 8
+
+// ValidatePassword returns an EINVALID error if password is too weak to be
+// used for an account.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return Errorf(EINVALID, "Password must be at least %d characters.", MinPasswordLength)
+	} else if len(password) > 72 {
+		return Errorf(EINVALID, "Password must be at most 72 bytes.")
+	}
+	return nil
+}
+
+// HashPassword returns a bcrypt hash of password suitable for storage.
+func HashPassword(password string) (string, error) {
+	if len(password) > 72 {
+		return "", Errorf(EINVALID, "Password must be at most 72 bytes.")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// ComparePassword returns true if password matches a hash from HashPassword.
+func ComparePassword(hash, password string) bool {
+	if hash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,38 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// SessionDuration is how long a user stays signed in.
+const SessionDuration = 30 * 24 * time.Hour
+
+// Session represents a signed in user. Only a hash of the token is stored so
+// the plaintext Token is only available right after Create.
+type Session struct {
+	ID     int   `json:"id"`
+	UserID int   `json:"user_id"`
+	User   *User `json:"user"`
+
+	Token     string `json:"-"`
+	TokenHash string `json:"-"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsExpired returns true if the session can no longer be used.
+func (s *Session) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+type SessionService interface {
+	// FindSessionByToken returns the unexpired session for token, along with
+	// its user, or an ENOTFOUND error.
+	FindSessionByToken(ctx context.Context, token string) (*Session, error)
+
+	// Create starts a new session for session.UserID and sets its Token.
+	Create(ctx context.Context, session *Session) error
+	Delete(ctx context.Context, id int) error
+}
@@ -3,14 +3,101 @@ package suss
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	SlugMinLength = 3
+	SlugMaxLength = 64
+)
+
+// reservedSlugs holds the top-level paths routed by the http server. A slug
+// matching one of these would never be reachable so they cannot be used.
+var reservedSlugs = map[string]struct{}{
+	"admin":      {},
+	"api":        {},
+	"api-keys":   {},
+	"assets":     {},
+	"links":      {},
+	"login":      {},
+	"logout":     {},
+	"manage":     {},
+	"preview":    {},
+	"qrcode":     {},
+	"register":   {},
+	"report":     {},
+	"shorten":    {},
+	"workspaces": {},
+}
+
+// IsReservedSlug returns true if slug collides with a reserved path.
+func IsReservedSlug(slug string) bool {
+	_, ok := reservedSlugs[strings.ToLower(slug)]
+	return ok
+}
+
+// ValidateSlug returns an EINVALID error if slug is not usable as a short url path.
+func ValidateSlug(slug string) error {
+	if slug == "" {
+		return Errorf(EINVALID, "Slug required.")
+	} else if len(slug) < SlugMinLength || len(slug) > SlugMaxLength {
+		return Errorf(EINVALID, "Slug must be between %d and %d characters.", SlugMinLength, SlugMaxLength)
+	}
+	for _, c := range slug {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return Errorf(EINVALID, "Slug may only contain letters, numbers, dashes and underscores.")
+		}
+	}
+	if IsReservedSlug(slug) {
+		return Errorf(EINVALID, "Slug %q is reserved.", slug)
+	}
+	return nil
+}
+
 type ShortURL struct {
-	ID        int    `json:"id"`
-	Slug      string `json:"slug"`
-	LongURL   string `json:"long_url"`
-	SecretKey string `json:"secret_key"`
+	ID      int    `json:"id"`
+	Slug    string `json:"slug"`
+	LongURL string `json:"long_url"`
+
+	// Secret used to manage the short url. Only a hash is stored so the
+	// plaintext SecretKey is only available right after Create.
+	SecretKey     string `json:"secret_key,omitempty"`
+	SecretKeyHash string `json:"-"`
+
+	// Optional lifetime controls. A zero ExpiresAt never expires and a zero
+	// MaxClicks allows an unlimited number of visits.
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxClicks int       `json:"max_clicks"`
+
+	// Optional password visitors must enter before being redirected. Password
+	// is only used to set a new password and is never stored or returned.
+	Password     string `json:"-"`
+	PasswordHash string `json:"-"`
+
+	// Optional email of the owner, used to send a new manage link once the
+	// secret key is lost.
+	Email string `json:"-"`
+
+	// User who created the short url, zero if created anonymously. Owners can
+	// manage their short urls without the secret key.
+	OwnerID int `json:"owner_id,omitempty"`
+
+	// Workspace the short url belongs to, zero if personal or anonymous. Short
+	// urls in a workspace are managed by its members according to their role.
+	WorkspaceID int `json:"workspace_id,omitempty"`
+
+	// Threat the destination was flagged for by the URLChecker since it was
+	// created, such as "MALWARE". Flagged short urls show a warning instead
+	// of redirecting.
+	Threat string `json:"threat,omitempty"`
+
+	// Time an administrator disabled the short url, zero if enabled. Disabled
+	// short urls no longer redirect.
+	DisabledAt time.Time `json:"disabled_at"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -20,16 +107,182 @@ func (s *ShortURL) ShortURL(host string) string {
 	return fmt.Sprintf("%s/%s", host, s.Slug)
 }
 
+// Validate returns an error if the short url contains invalid fields. The long
+// url is normalized in place according to DefaultURLPolicy.
 func (s *ShortURL) Validate() error {
+	if err := ValidateSlug(s.Slug); err != nil {
+		return err
+	}
+	longURL, err := DefaultURLPolicy.Normalize(s.LongURL)
+	if err != nil {
+		return err
+	}
+	s.LongURL = longURL
+	if s.Email != "" {
+		if s.Email, err = NormalizeEmail(s.Email); err != nil {
+			return err
+		}
+	}
+	if s.MaxClicks < 0 {
+		return Errorf(EINVALID, "Max clicks must not be negative.")
+	}
+	if !s.ExpiresAt.IsZero() && !s.ExpiresAt.After(s.CreatedAt) {
+		return Errorf(EINVALID, "Expiration date must be after the creation date.")
+	}
 	return nil
 }
 
+// Host returns the lowercase host name of the destination, without the port.
+func (s *ShortURL) Host() string {
+	u, err := url.Parse(s.LongURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// HasPassword returns true if visitors must enter a password to be redirected.
+func (s *ShortURL) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// IsDisabled returns true if an administrator disabled the short url.
+func (s *ShortURL) IsDisabled() bool {
+	return !s.DisabledAt.IsZero()
+}
+
+// IsExpired returns true if the short url has passed its expiration date or
+// has already been visited the maximum number of times allowed.
+func (s *ShortURL) IsExpired(now time.Time, clicks int) bool {
+	if !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt) {
+		return true
+	}
+	if s.MaxClicks > 0 && clicks >= s.MaxClicks {
+		return true
+	}
+	return false
+}
+
+// IsOwnedBy returns true if the short url was created by the user. Ownership
+// only applies to short urls outside of a workspace.
+func (s *ShortURL) IsOwnedBy(user *User) bool {
+	return user != nil && s.WorkspaceID == 0 && s.OwnerID != 0 && s.OwnerID == user.ID
+}
+
+// Sort orders accepted by ShortURLFilter. A leading "-" sorts descending.
+const (
+	ShortURLSortCreatedAt     = "created_at"
+	ShortURLSortCreatedAtDesc = "-created_at"
+	ShortURLSortUpdatedAt     = "updated_at"
+	ShortURLSortUpdatedAtDesc = "-updated_at"
+)
+
+// Actions applied to many short urls at once by BulkApply.
+const (
+	ShortURLBulkDisable = "disable"
+	ShortURLBulkEnable  = "enable"
+	ShortURLBulkDelete  = "delete"
+)
+
+// RecoveryTokenTTL is how long a recovery token can be redeemed for.
+const RecoveryTokenTTL = time.Hour
+
+// LongURLSearchLimit is the maximum number of short urls decrypted by a
+// substring search of their destination.
+const LongURLSearchLimit = 1000
+
 type ShortURLFilter struct {
-	Slug *string `json:"slug"`
+	ID      *int    `json:"id"`
+	Slug    *string `json:"slug"`
+	OwnerID *int    `json:"owner_id"`
+
+	// Filter by workspace, zero matches short urls outside of any workspace.
+	// Filtering by a workspace requires the current user to be a member.
+	WorkspaceID *int `json:"workspace_id"`
+
+	// Filter by a substring of the destination or its exact host name.
+	// Destinations are encrypted, so a substring search requires an owner or
+	// workspace filter unless the current user is an administrator, and only
+	// the first LongURLSearchLimit short urls in sort order are searched.
+	LongURL *string `json:"long_url"`
+	Host    *string `json:"host"`
+
+	// Filter by creation date, inclusive of CreatedAfter and exclusive of CreatedBefore.
+	CreatedAfter  *time.Time `json:"created_after"`
+	CreatedBefore *time.Time `json:"created_before"`
+
+	// Sort order, defaults to newest first.
+	Sort string `json:"sort"`
+
+	// Restrict to a subset of the results.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// ShortURLUpdate represents a set of fields to be updated via Update().
+type ShortURLUpdate struct {
+	LongURL   *string `json:"long_url"`
+	MaxClicks *int    `json:"max_clicks"`
+
+	// Sets a new expiration date, which must be in the future. The zero time
+	// removes the expiration.
+	ExpiresAt *time.Time `json:"expires_at"`
+
+	// Sets a new password, an empty string removes the password.
+	Password *string `json:"password"`
+
+	// Sets the owner email, an empty string removes it.
+	Email *string `json:"email"`
 }
 
 type ShortURLService interface {
+	// FindShortUrls returns the short urls matching filter. Administrators can
+	// find any short url. Other users must be signed in and only find short
+	// urls of workspaces they are a member of, or their own personal ones when
+	// the filter has no workspace.
 	FindShortUrls(ctx context.Context, filter ShortURLFilter) ([]*ShortURL, int, error)
 	FindDialBySlug(ctx context.Context, slug string) (*ShortURL, error)
+
+	// FindShortUrlBySecretKey returns the short url for slug if secretKey is
+	// its secret key, or an EUNAUTHORIZED error otherwise.
+	FindShortUrlBySecretKey(ctx context.Context, slug, secretKey string) (*ShortURL, error)
+
+	// Create creates a short url. Creating it in a workspace requires the
+	// current user to be at least an editor of the workspace.
 	Create(ctx context.Context, shortURL *ShortURL) error
+
+	// Update, RotateSecretKey and Delete of a short url in a workspace require
+	// the current user to be at least an editor of the workspace, or an
+	// administrator.
+	Update(ctx context.Context, id int, upd ShortURLUpdate) (*ShortURL, error)
+
+	// RotateSecretKey replaces the secret key of a short url, invalidating the
+	// previous one. The new plaintext SecretKey is set on the returned short url.
+	RotateSecretKey(ctx context.Context, id int) (*ShortURL, error)
+
+	// CreateRecoveryToken issues a single-use token to recover the manage link
+	// of the short url for slug, replacing any previous token. It returns an
+	// EUNAUTHORIZED error unless email is the owner email of a short url
+	// outside of any workspace. The secret key keeps working until the token
+	// is redeemed by RecoverShortUrl.
+	CreateRecoveryToken(ctx context.Context, slug, email string) (*ShortURL, string, error)
+
+	// RecoverShortUrl redeems a recovery token for the short url for slug,
+	// rotating its secret key as RotateSecretKey does. It returns an
+	// EUNAUTHORIZED error if the token is invalid, used or expired.
+	RecoverShortUrl(ctx context.Context, slug, token string) (*ShortURL, error)
+
+	Delete(ctx context.Context, id int) error
+
+	// Disable stops a short url from redirecting, resolving any open abuse
+	// reports for it, and Enable lets it redirect again. Only administrators
+	// can disable or enable short urls.
+	Disable(ctx context.Context, id int) (*ShortURL, error)
+	Enable(ctx context.Context, id int) (*ShortURL, error)
+
+	// BulkApply disables, enables or deletes the short urls with the given ids
+	// in a single transaction, so either all of them change or none do. Short
+	// urls that no longer exist are skipped. It returns the number of short
+	// urls found. Only administrators can apply bulk actions.
+	BulkApply(ctx context.Context, action string, ids []int) (int, error)
 }
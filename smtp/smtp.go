@@ -0,0 +1,131 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// SendTimeout is the time allowed to deliver an email to the SMTP server when
+// the context has no earlier deadline.
+const SendTimeout = 10 * time.Second
+
+// Mailer sends emails through an SMTP server. STARTTLS is used whenever the
+// server supports it and credentials are only sent over TLS or to localhost,
+// so a local SMTP stand-in can be used during development.
+type Mailer struct {
+	// address of the SMTP server, e.g. "smtp.example.com:587"
+	Addr string
+
+	// optional credentials for PLAIN authentication
+	Username string
+	Password string
+
+	// address emails are sent from
+	From string
+}
+
+// NewMailer returns a new instance of Mailer.
+func NewMailer(addr, from string) *Mailer {
+	return &Mailer{
+		Addr: addr,
+		From: from,
+	}
+}
+
+// SendMail delivers m to the SMTP server.
+func (s *Mailer) SendMail(ctx context.Context, m *suss.Mail) error {
+	from, err := netmail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := netmail.ParseAddress(m.To)
+	if err != nil {
+		return suss.Errorf(suss.EINVALID, "Invalid email address.")
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SendTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// net/smtp has no context support, bound the whole exchange instead
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(formatMessage(from, to, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// formatMessage builds the headers & plain text body of m.
+func formatMessage(from, to *netmail.Address, m *suss.Mail) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+
+	// normalize line endings, lines starting with a dot are escaped by the
+	// data writer of net/smtp
+	body := strings.ReplaceAll(m.Body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
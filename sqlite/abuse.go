@@ -0,0 +1,207 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type AbuseReportService struct {
+	db *DB
+}
+
+func NewAbuseReportService(db *DB) *AbuseReportService {
+	return &AbuseReportService{
+		db: db,
+	}
+}
+
+func (s *AbuseReportService) FindAbuseReports(ctx context.Context, filter suss.AbuseReportFilter) ([]*suss.AbuseReport, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	reports, n, err := findAbuseReports(ctx, tx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// attach the reported short urls
+	for _, report := range reports {
+		if report.ShortURL, err = findShortUrlByID(ctx, tx, report.ShortURLID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return reports, n, nil
+}
+
+func (s *AbuseReportService) Create(ctx context.Context, report *suss.AbuseReport) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := abuseReportCreate(ctx, tx, report); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *AbuseReportService) Disable(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	report, err := findAbuseReportByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	// disabling the short url resolves every open report for it
+	if _, err := shortUrlDisable(ctx, tx, report.ShortURLID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *AbuseReportService) Dismiss(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	report, err := findAbuseReportByID(ctx, tx, id)
+	if err != nil {
+		return err
+	} else if report.Status != suss.AbuseReportOpen {
+		return suss.Errorf(suss.ECONFLICT, "This report was already resolved.")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE abuse_reports SET status = ?, resolved_at = ? WHERE id = ?
+	`, suss.AbuseReportDismissed, (*NullTime)(&tx.now), id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func abuseReportCreate(ctx context.Context, tx *Tx, r *suss.AbuseReport) error {
+	r.Comment = strings.TrimSpace(r.Comment)
+	r.Status = suss.AbuseReportOpen
+	r.CreatedAt = tx.now
+
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	// verify the short url exists
+	if _, err := findShortUrlByID(ctx, tx, r.ShortURLID); err != nil {
+		return err
+	}
+
+	// one open report per reporter is enough to get a link reviewed
+	status := suss.AbuseReportOpen
+	reports, _, err := findAbuseReports(ctx, tx, suss.AbuseReportFilter{ShortURLID: &r.ShortURLID, Status: &status})
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if r.IPHash != "" && report.IPHash == r.IPHash {
+			return suss.Errorf(suss.ECONFLICT, "You already reported this link.")
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO abuse_reports (short_url_id, reason, comment, ip_hash, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, r.ShortURLID, r.Reason, r.Comment, r.IPHash, r.Status, (*NullTime)(&r.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = int(id)
+
+	return nil
+}
+
+func findAbuseReportByID(ctx context.Context, tx *Tx, id int) (*suss.AbuseReport, error) {
+	reports, _, err := findAbuseReports(ctx, tx, suss.AbuseReportFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(reports) == 0 {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Abuse report not found."}
+	}
+	return reports[0], nil
+}
+
+func findAbuseReports(ctx context.Context, tx *Tx, filter suss.AbuseReportFilter) ([]*suss.AbuseReport, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.ShortURLID; v != nil {
+		where, args = append(where, "short_url_id = ?"), append(args, *v)
+	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, "status = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, short_url_id, reason, comment, ip_hash, status, created_at, resolved_at, COUNT(*) OVER()
+		FROM abuse_reports
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	reports := make([]*suss.AbuseReport, 0)
+	for rows.Next() {
+		var report suss.AbuseReport
+		if err := rows.Scan(
+			&report.ID,
+			&report.ShortURLID,
+			&report.Reason,
+			&report.Comment,
+			&report.IPHash,
+			&report.Status,
+			(*NullTime)(&report.CreatedAt),
+			(*NullTime)(&report.ResolvedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, &report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return reports, n, nil
+}
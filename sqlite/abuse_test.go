@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// MustCreateAbuseReport reports shortUrl as phishing from the reporter ipHash.
+func MustCreateAbuseReport(tb testing.TB, db *DB, shortUrl *suss.ShortURL, ipHash string) *suss.AbuseReport {
+	tb.Helper()
+	report := &suss.AbuseReport{ShortURLID: shortUrl.ID, Reason: suss.AbuseReasonPhishing, IPHash: ipHash}
+	if err := NewAbuseReportService(db).Create(context.Background(), report); err != nil {
+		tb.Fatal(err)
+	}
+	return report
+}
+
+func TestAbuseReportService_Create(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		report := MustCreateAbuseReport(t, db, shortUrl, "ip0")
+
+		ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+		if reports, n, err := NewAbuseReportService(db).FindAbuseReports(ctx, suss.AbuseReportFilter{}); err != nil {
+			t.Fatal(err)
+		} else if n != 1 || reports[0].ID != report.ID || reports[0].Status != suss.AbuseReportOpen {
+			t.Fatalf("n=%d, reports=%#v", n, reports)
+		} else if reports[0].ShortURL == nil || reports[0].ShortURL.LongURL != "https://example.com/" {
+			t.Fatalf("ShortURL=%#v", reports[0].ShortURL)
+		}
+	})
+
+	t.Run("ErrDuplicate", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		MustCreateAbuseReport(t, db, shortUrl, "ip0")
+
+		// one open report per reporter, others can still report the link
+		if err := NewAbuseReportService(db).Create(context.Background(), &suss.AbuseReport{ShortURLID: shortUrl.ID, Reason: suss.AbuseReasonSpam, IPHash: "ip0"}); suss.ErrorCode(err) != suss.ECONFLICT {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		MustCreateAbuseReport(t, db, shortUrl, "ip1")
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		if err := NewAbuseReportService(db).Create(context.Background(), &suss.AbuseReport{ShortURLID: shortUrl.ID, Reason: "boring"}); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := NewAbuseReportService(db).Create(context.Background(), &suss.AbuseReport{ShortURLID: 1000, Reason: suss.AbuseReasonSpam}); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestAbuseReportService_Resolve(t *testing.T) {
+	ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+
+	t.Run("Disable", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAbuseReportService(db)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		report0 := MustCreateAbuseReport(t, db, shortUrl, "ip0")
+		MustCreateAbuseReport(t, db, shortUrl, "ip1")
+
+		// disabling the link resolves every open report for it
+		if err := s.Disable(ctx, report0.ID); err != nil {
+			t.Fatal(err)
+		}
+		status := suss.AbuseReportDisabled
+		if _, n, err := s.FindAbuseReports(ctx, suss.AbuseReportFilter{Status: &status}); err != nil {
+			t.Fatal(err)
+		} else if n != 2 {
+			t.Fatalf("n=%d", n)
+		}
+		if other, err := NewShortURLService(db).FindDialBySlug(context.Background(), shortUrl.Slug); err != nil {
+			t.Fatal(err)
+		} else if !other.IsDisabled() {
+			t.Fatal("expected short url to be disabled")
+		}
+	})
+
+	t.Run("Dismiss", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAbuseReportService(db)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		report := MustCreateAbuseReport(t, db, shortUrl, "ip0")
+
+		if err := s.Dismiss(ctx, report.ID); err != nil {
+			t.Fatal(err)
+		} else if err := s.Dismiss(ctx, report.ID); suss.ErrorCode(err) != suss.ECONFLICT {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if other, err := NewShortURLService(db).FindDialBySlug(context.Background(), shortUrl.Slug); err != nil {
+			t.Fatal(err)
+		} else if other.IsDisabled() {
+			t.Fatal("short url disabled by dismissing a report")
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAbuseReportService(db)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		report := MustCreateAbuseReport(t, db, shortUrl, "ip0")
+		_, userCtx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+		if _, _, err := s.FindAbuseReports(userCtx, suss.AbuseReportFilter{}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Disable(userCtx, report.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Dismiss(userCtx, report.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// APIKeyPrefix starts every API key so they are easy to recognise, e.g. by
+// secret scanners.
+const APIKeyPrefix = "suss_"
+
+// apiKeyLastUsedInterval is how often the last used time of a key is written,
+// so busy keys do not write on every request.
+const apiKeyLastUsedInterval = time.Minute
+
+type APIKeyService struct {
+	db *DB
+}
+
+func NewAPIKeyService(db *DB) *APIKeyService {
+	return &APIKeyService{
+		db: db,
+	}
+}
+
+func (s *APIKeyService) FindAPIKeys(ctx context.Context, filter suss.APIKeyFilter) ([]*suss.APIKey, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findAPIKeys(ctx, tx, "user_id = ?", []interface{}{suss.UserIDFromContext(ctx)}, filter)
+}
+
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*suss.APIKey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Invalid API key.")
+	}
+
+	keys, _, err := findAPIKeys(ctx, tx, "key_hash = ?", []interface{}{tx.db.hash(key)}, suss.APIKeyFilter{})
+	if err != nil {
+		return nil, err
+	} else if len(keys) == 0 {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Invalid API key.")
+	}
+	apiKey := keys[0]
+
+	if apiKey.User, err = findUserByID(ctx, tx, apiKey.UserID); err != nil {
+		return nil, err
+	}
+
+	if tx.now.Sub(apiKey.LastUsedAt) < apiKeyLastUsedInterval {
+		return apiKey, nil
+	}
+
+	apiKey.LastUsedAt = tx.now
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, (*NullTime)(&apiKey.LastUsedAt), apiKey.ID); err != nil {
+		return nil, err
+	}
+
+	return apiKey, tx.Commit()
+}
+
+func (s *APIKeyService) Create(ctx context.Context, key *suss.APIKey) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := apiKeyCreate(ctx, tx, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *APIKeyService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// users can only revoke their own keys
+	result, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, suss.UserIDFromContext(ctx))
+	if err != nil {
+		return err
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &suss.Error{Code: suss.ENOTFOUND, Message: "API key not found."}
+	}
+
+	return tx.Commit()
+}
+
+func apiKeyCreate(ctx context.Context, tx *Tx, k *suss.APIKey) error {
+	k.UserID = suss.UserIDFromContext(ctx)
+	if k.UserID == 0 {
+		return suss.Errorf(suss.EUNAUTHORIZED, "You must be signed in to create an API key.")
+	}
+
+	if err := k.Validate(); err != nil {
+		return err
+	}
+
+	// generate the key, only its hash and prefix are stored
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	k.Key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	k.KeyHash = tx.db.hash(k.Key)
+	k.Prefix = k.Key[:len(APIKeyPrefix)+6]
+
+	k.CreatedAt = tx.now
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO api_keys (user_id, name, key_hash, prefix, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, k.UserID, k.Name, k.KeyHash, k.Prefix, strings.Join(k.Scopes, " "), (*NullTime)(&k.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	k.ID = int(id)
+
+	return nil
+}
+
+// findAPIKeys returns the keys matching the where clause and filter, newest
+// first.
+func findAPIKeys(ctx context.Context, tx *Tx, where string, args []interface{}, filter suss.APIKeyFilter) ([]*suss.APIKey, int, error) {
+	if v := filter.ID; v != nil {
+		where, args = where+" AND id = ?", append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, name, key_hash, prefix, scopes, last_used_at, created_at, COUNT(*) OVER()
+		FROM api_keys
+		WHERE `+where+`
+		ORDER BY id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	keys := make([]*suss.APIKey, 0)
+	for rows.Next() {
+		var key suss.APIKey
+		var scopes string
+		if err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.KeyHash,
+			&key.Prefix,
+			&scopes,
+			(*NullTime)(&key.LastUsedAt),
+			(*NullTime)(&key.CreatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		key.Scopes = strings.Fields(scopes)
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return keys, n, nil
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAPIKeyService(db)
+		user, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+		key := &suss.APIKey{Name: "CI", Scopes: []string{suss.APIKeyScopeLinksWrite}}
+		if err := s.Create(ctx, key); err != nil {
+			t.Fatal(err)
+		} else if !strings.HasPrefix(key.Key, APIKeyPrefix) || !strings.HasPrefix(key.Key, key.Prefix) {
+			t.Fatalf("Key=%q, Prefix=%q", key.Key, key.Prefix)
+		}
+
+		// only a hash of the key is stored
+		var keyHash string
+		if err := db.db.QueryRow(`SELECT key_hash FROM api_keys WHERE id = ?`, key.ID).Scan(&keyHash); err != nil {
+			t.Fatal(err)
+		} else if keyHash == key.Key {
+			t.Fatal("key stored in plaintext")
+		}
+
+		other, err := s.Authenticate(context.Background(), key.Key)
+		if err != nil {
+			t.Fatal(err)
+		} else if other.ID != key.ID || other.User == nil || other.User.ID != user.ID {
+			t.Fatalf("unexpected key: %#v", other)
+		} else if other.LastUsedAt.IsZero() {
+			t.Fatal("expected last used time")
+		} else if !other.HasScope(suss.APIKeyScopeLinksWrite) || other.HasScope(suss.APIKeyScopeLinksRead) {
+			t.Fatalf("Scopes=%v", other.Scopes)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAPIKeyService(db)
+		_, ctx0 := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		_, ctx1 := MustCreateUser(t, db, &suss.User{Name: "John", Email: "john@example.com", Password: "password123"})
+
+		key := &suss.APIKey{Name: "CI", Scopes: suss.APIKeyScopes}
+		if err := s.Create(ctx0, key); err != nil {
+			t.Fatal(err)
+		}
+
+		// users only revoke their own keys
+		if err := s.Delete(ctx1, key.ID); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		} else if err := s.Delete(ctx0, key.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Authenticate(context.Background(), key.Key); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("FindAPIKeys", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAPIKeyService(db)
+		_, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if err := s.Create(ctx, &suss.APIKey{Name: "CI", Scopes: suss.APIKeyScopes}); err != nil {
+			t.Fatal(err)
+		}
+
+		if keys, n, err := s.FindAPIKeys(ctx, suss.APIKeyFilter{}); err != nil {
+			t.Fatal(err)
+		} else if n != 1 || keys[0].Key != "" || !slices.Equal(keys[0].Scopes, suss.APIKeyScopes) {
+			t.Fatalf("n=%d, keys=%#v", n, keys)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewAPIKeyService(db)
+		_, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+		if err := s.Create(ctx, &suss.APIKey{Name: "CI", Scopes: []string{"links:admin"}}); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Create(context.Background(), &suss.APIKey{Name: "CI", Scopes: suss.APIKeyScopes}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.Authenticate(context.Background(), APIKeyPrefix+"bogus"); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
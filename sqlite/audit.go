@@ -0,0 +1,211 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type AuditService struct {
+	db *DB
+}
+
+func NewAuditService(db *DB) *AuditService {
+	return &AuditService{
+		db: db,
+	}
+}
+
+func (s *AuditService) FindAuditEvents(ctx context.Context, filter suss.AuditEventFilter) ([]*suss.AuditEvent, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	return findAuditEvents(ctx, tx, filter)
+}
+
+// auditShortURL is the snapshot of a short url recorded in the audit log.
+// Secrets are left out, only whether a password or email is set is kept.
+type auditShortURL struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug"`
+	LongURL     string    `json:"long_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	MaxClicks   int       `json:"max_clicks"`
+	HasPassword bool      `json:"has_password"`
+	HasEmail    bool      `json:"has_email"`
+	OwnerID     int       `json:"owner_id,omitempty"`
+	WorkspaceID int       `json:"workspace_id,omitempty"`
+	Threat      string    `json:"threat,omitempty"`
+	DisabledAt  time.Time `json:"disabled_at"`
+}
+
+// marshalAuditShortURL returns the encrypted json snapshot of s, or an empty
+// string if s is nil.
+func marshalAuditShortURL(tx *Tx, s *suss.ShortURL) (string, error) {
+	if s == nil {
+		return "", nil
+	}
+
+	buf, err := json.Marshal(auditShortURL{
+		ID:          s.ID,
+		Slug:        s.Slug,
+		LongURL:     s.LongURL,
+		ExpiresAt:   s.ExpiresAt,
+		MaxClicks:   s.MaxClicks,
+		HasPassword: s.HasPassword(),
+		HasEmail:    s.Email != "",
+		OwnerID:     s.OwnerID,
+		WorkspaceID: s.WorkspaceID,
+		Threat:      s.Threat,
+		DisabledAt:  s.DisabledAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return tx.db.encrypt(string(buf))
+}
+
+// auditEventCreate records a change of a short url by the actor of ctx.
+// before is nil for created short urls and after is nil for deleted ones.
+func auditEventCreate(ctx context.Context, tx *Tx, action string, before, after *suss.ShortURL) error {
+	e := &suss.AuditEvent{
+		Action:    action,
+		ActorType: suss.AuditActorAnonymous,
+		CreatedAt: tx.now,
+	}
+
+	// identify the actor
+	if key := suss.APIKeyFromContext(ctx); key != nil {
+		e.ActorType, e.ActorUserID, e.ActorAPIKeyID = suss.AuditActorAPIKey, key.UserID, key.ID
+	} else if user := suss.UserFromContext(ctx); user != nil && user.ID != 0 {
+		e.ActorType, e.ActorUserID = suss.AuditActorUser, user.ID
+	} else if user != nil && user.IsAdmin {
+		e.ActorType = suss.AuditActorAdminToken
+	}
+	if info := suss.RequestInfoFromContext(ctx); info != nil {
+		e.ActorIPHash, e.RequestID = info.IPHash, info.ID
+	} else if e.ActorType == suss.AuditActorAnonymous {
+		e.ActorType = suss.AuditActorSystem
+	}
+
+	if s := after; s != nil {
+		e.ShortURLID, e.Slug = s.ID, s.Slug
+	} else if s := before; s != nil {
+		e.ShortURLID, e.Slug = s.ID, s.Slug
+	}
+
+	beforeValue, err := marshalAuditShortURL(tx, before)
+	if err != nil {
+		return err
+	}
+	afterValue, err := marshalAuditShortURL(tx, after)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_events (action, actor_type, actor_user_id, actor_api_key_id, actor_ip_hash, short_url_id, slug, before_value, after_value, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Action, e.ActorType, sql.NullInt64{Int64: int64(e.ActorUserID), Valid: e.ActorUserID != 0}, sql.NullInt64{Int64: int64(e.ActorAPIKeyID), Valid: e.ActorAPIKeyID != 0}, e.ActorIPHash, e.ShortURLID, e.Slug, beforeValue, afterValue, e.RequestID, (*NullTime)(&e.CreatedAt))
+	return err
+}
+
+func findAuditEvents(ctx context.Context, tx *Tx, filter suss.AuditEventFilter) ([]*suss.AuditEvent, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.Action; v != nil {
+		where, args = append(where, "action = ?"), append(args, *v)
+	}
+	if v := filter.ActorType; v != nil {
+		where, args = append(where, "actor_type = ?"), append(args, *v)
+	}
+	if v := filter.ActorUserID; v != nil {
+		where, args = append(where, "actor_user_id = ?"), append(args, *v)
+	}
+	if v := filter.ShortURLID; v != nil {
+		where, args = append(where, "short_url_id = ?"), append(args, *v)
+	}
+	if v := filter.Slug; v != nil {
+		where, args = append(where, "slug = ?"), append(args, *v)
+	}
+	if v := filter.RequestID; v != nil {
+		where, args = append(where, "request_id = ?"), append(args, *v)
+	}
+	if v := filter.CreatedAfter; v != nil {
+		where, args = append(where, "created_at >= ?"), append(args, (*NullTime)(v))
+	}
+	if v := filter.CreatedBefore; v != nil {
+		where, args = append(where, "created_at < ?"), append(args, (*NullTime)(v))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, action, actor_type, COALESCE(actor_user_id, 0), COALESCE(actor_api_key_id, 0), actor_ip_hash, short_url_id, slug, before_value, after_value, request_id, created_at, COUNT(*) OVER()
+		FROM audit_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	events := make([]*suss.AuditEvent, 0)
+	for rows.Next() {
+		var e suss.AuditEvent
+		var beforeValue, afterValue string
+		if err := rows.Scan(
+			&e.ID,
+			&e.Action,
+			&e.ActorType,
+			&e.ActorUserID,
+			&e.ActorAPIKeyID,
+			&e.ActorIPHash,
+			&e.ShortURLID,
+			&e.Slug,
+			&beforeValue,
+			&afterValue,
+			&e.RequestID,
+			(*NullTime)(&e.CreatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		if e.Before, err = decryptAuditValue(tx, beforeValue); err != nil {
+			return nil, 0, err
+		}
+		if e.After, err = decryptAuditValue(tx, afterValue); err != nil {
+			return nil, 0, err
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, n, nil
+}
+
+// decryptAuditValue returns the json snapshot of an audit value, or json null
+// if there is none.
+func decryptAuditValue(tx *Tx, v string) (json.RawMessage, error) {
+	if v == "" {
+		return json.RawMessage("null"), nil
+	}
+	v, err := tx.db.decrypt(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(v), nil
+}
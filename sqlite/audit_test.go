@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// MustFindAuditEvents returns the audit events with action.
+func MustFindAuditEvents(tb testing.TB, db *DB, action string) []*suss.AuditEvent {
+	tb.Helper()
+	ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+	events, _, err := NewAuditService(db).FindAuditEvents(ctx, suss.AuditEventFilter{Action: &action})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return events
+}
+
+func TestAuditEvents(t *testing.T) {
+	t.Run("Lifecycle", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewShortURLService(db)
+		ctx := suss.NewContextWithRequestInfo(context.Background(), &suss.RequestInfo{ID: "req-1", IPHash: "ip0"})
+		shortUrl := MustCreateShortURL(t, ctx, db, &suss.ShortURL{LongURL: "https://example.com/", Password: "hunter22"})
+
+		longURL := "https://example.com/new"
+		if _, err := s.Update(ctx, shortUrl.ID, suss.ShortURLUpdate{LongURL: &longURL}); err != nil {
+			t.Fatal(err)
+		} else if err := s.Delete(ctx, shortUrl.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		// created short urls have no before and deleted ones no after
+		create := MustFindAuditEvents(t, db, suss.AuditShortURLCreate)
+		update := MustFindAuditEvents(t, db, suss.AuditShortURLUpdate)
+		del := MustFindAuditEvents(t, db, suss.AuditShortURLDelete)
+		if len(create) != 1 || len(update) != 1 || len(del) != 1 {
+			t.Fatalf("len=%d, %d, %d", len(create), len(update), len(del))
+		} else if string(create[0].Before) != "null" || string(create[0].After) == "null" {
+			t.Fatalf("Before=%s, After=%s", create[0].Before, create[0].After)
+		} else if string(del[0].Before) == "null" || string(del[0].After) != "null" {
+			t.Fatalf("Before=%s, After=%s", del[0].Before, del[0].After)
+		} else if !strings.Contains(string(update[0].After), longURL) {
+			t.Fatalf("After=%s", update[0].After)
+		}
+
+		for _, e := range []*suss.AuditEvent{create[0], update[0], del[0]} {
+			if e.ActorType != suss.AuditActorAnonymous || e.ActorIPHash != "ip0" || e.RequestID != "req-1" {
+				t.Fatalf("ActorType=%q, ActorIPHash=%q, RequestID=%q", e.ActorType, e.ActorIPHash, e.RequestID)
+			} else if e.ShortURLID != shortUrl.ID || e.Slug != shortUrl.Slug {
+				t.Fatalf("ShortURLID=%d, Slug=%q", e.ShortURLID, e.Slug)
+			}
+		}
+
+		// secrets are never recorded
+		if v := string(create[0].After); strings.Contains(v, "hunter22") || strings.Contains(v, shortUrl.PasswordHash) || strings.Contains(v, shortUrl.SecretKeyHash) {
+			t.Fatalf("secret recorded: %s", v)
+		}
+	})
+
+	t.Run("Encrypted", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/signed?token=abc"})
+
+		var after string
+		if err := db.db.QueryRow(`SELECT after_value FROM audit_events`).Scan(&after); err != nil {
+			t.Fatal(err)
+		} else if !strings.HasPrefix(after, encryptedPrefix) || strings.Contains(after, "token") {
+			t.Fatalf("after_value=%q", after)
+		}
+	})
+
+	t.Run("Actors", func(t *testing.T) {
+		db := MustOpenDB(t)
+		user, userCtx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		key := &suss.APIKey{ID: 7, UserID: user.ID}
+		info := &suss.RequestInfo{ID: "req-1", IPHash: "ip0"}
+		adminCtx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+
+		for _, tt := range []struct {
+			ctx  context.Context
+			want suss.AuditEvent
+		}{
+			{context.Background(), suss.AuditEvent{ActorType: suss.AuditActorSystem}},
+			{suss.NewContextWithRequestInfo(context.Background(), info), suss.AuditEvent{ActorType: suss.AuditActorAnonymous}},
+			{suss.NewContextWithRequestInfo(userCtx, info), suss.AuditEvent{ActorType: suss.AuditActorUser, ActorUserID: user.ID}},
+			{suss.NewContextWithAPIKey(suss.NewContextWithRequestInfo(userCtx, info), key), suss.AuditEvent{ActorType: suss.AuditActorAPIKey, ActorUserID: user.ID, ActorAPIKeyID: key.ID}},
+			{suss.NewContextWithUser(suss.NewContextWithRequestInfo(context.Background(), info), &suss.User{IsAdmin: true}), suss.AuditEvent{ActorType: suss.AuditActorAdminToken}},
+		} {
+			shortUrl := MustCreateShortURL(t, tt.ctx, db, &suss.ShortURL{LongURL: "https://example.com/"})
+			events, _, err := NewAuditService(db).FindAuditEvents(adminCtx, suss.AuditEventFilter{ShortURLID: &shortUrl.ID})
+			if err != nil {
+				t.Fatal(err)
+			} else if len(events) != 1 {
+				t.Fatalf("len(events)=%d", len(events))
+			} else if e := events[0]; e.ActorType != tt.want.ActorType || e.ActorUserID != tt.want.ActorUserID || e.ActorAPIKeyID != tt.want.ActorAPIKeyID {
+				t.Fatalf("ActorType=%q, ActorUserID=%d, ActorAPIKeyID=%d, want %q", e.ActorType, e.ActorUserID, e.ActorAPIKeyID, tt.want.ActorType)
+			}
+		}
+
+		// filter by actor
+		actorType := suss.AuditActorUser
+		if _, n, err := NewAuditService(db).FindAuditEvents(adminCtx, suss.AuditEventFilter{ActorType: &actorType, ActorUserID: &user.ID}); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d", n)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		db := MustOpenDB(t)
+		_, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if _, _, err := NewAuditService(db).FindAuditEvents(ctx, suss.AuditEventFilter{}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("RotateSecretKey", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		if _, err := NewShortURLService(db).RotateSecretKey(context.Background(), shortUrl.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		// the short url is recorded as it was before the rotation
+		events := MustFindAuditEvents(t, db, suss.AuditShortURLRotateSecretKey)
+		if len(events) != 1 {
+			t.Fatalf("len(events)=%d", len(events))
+		} else if string(events[0].Before) == "null" || string(events[0].After) == "null" {
+			t.Fatalf("Before=%s, After=%s", events[0].Before, events[0].After)
+		} else if got, want := events[0].ShortURLID, shortUrl.ID; got != want {
+			t.Fatalf("ShortURLID=%d, want %d", got, want)
+		}
+	})
+
+	t.Run("WorkspaceDelete", func(t *testing.T) {
+		db := MustOpenDB(t)
+		_, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		workspace := &suss.Workspace{Name: "Marketing"}
+		if err := NewWorkspaceService(db).Create(ctx, workspace); err != nil {
+			t.Fatal(err)
+		}
+		shortUrl0 := MustCreateShortURL(t, ctx, db, &suss.ShortURL{LongURL: "https://example.com/0", WorkspaceID: workspace.ID})
+		shortUrl1 := MustCreateShortURL(t, ctx, db, &suss.ShortURL{LongURL: "https://example.com/1", WorkspaceID: workspace.ID})
+		MustCreateShortURL(t, ctx, db, &suss.ShortURL{LongURL: "https://example.com/personal"})
+
+		if err := NewWorkspaceService(db).Delete(ctx, workspace.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		// every detached link has an update event
+		events := MustFindAuditEvents(t, db, suss.AuditShortURLUpdate)
+		if len(events) != 2 {
+			t.Fatalf("len(events)=%d", len(events))
+		}
+		ids := make(map[int]bool)
+		for _, e := range events {
+			ids[e.ShortURLID] = true
+
+			var before, after struct {
+				WorkspaceID int `json:"workspace_id"`
+			}
+			if err := json.Unmarshal(e.Before, &before); err != nil {
+				t.Fatal(err)
+			} else if err := json.Unmarshal(e.After, &after); err != nil {
+				t.Fatal(err)
+			} else if before.WorkspaceID != workspace.ID || after.WorkspaceID != 0 {
+				t.Fatalf("WorkspaceID before=%d, after=%d", before.WorkspaceID, after.WorkspaceID)
+			}
+		}
+		if !ids[shortUrl0.ID] || !ids[shortUrl1.ID] {
+			t.Fatalf("unexpected short urls: %v", ids)
+		}
+	})
+}
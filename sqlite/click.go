@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type ClickService struct {
+	db *DB
+}
+
+func NewClickService(db *DB) *ClickService {
+	return &ClickService{
+		db: db,
+	}
+}
+
+func (s *ClickService) Create(ctx context.Context, click *suss.Click) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := clickCreate(ctx, tx, click); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *ClickService) FindClicks(ctx context.Context, filter suss.ClickFilter) ([]*suss.Click, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findClicks(ctx, tx, filter)
+}
+
+func (s *ClickService) FindClickStats(ctx context.Context, shortURLID int) (*suss.ClickStats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findClickStats(ctx, tx, shortURLID)
+}
+
+func clickCreate(ctx context.Context, tx *Tx, c *suss.Click) error {
+	// set created at
+	c.CreatedAt = tx.now
+
+	// keep client supplied headers to a bounded size
+	c.Referrer = truncateString(c.Referrer, suss.MaxClickFieldLength)
+	c.UserAgent = truncateString(c.UserAgent, suss.MaxClickFieldLength)
+
+	// validate the click
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	// only insert the click while the short url is under its maximum, if any
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO clicks (short_url_id, referrer, user_agent, ip_hash, created_at)
+		SELECT ?, ?, ?, ?, ?
+		FROM short_urls
+		WHERE id = ?
+		  AND (max_clicks = 0 OR (SELECT COUNT(*) FROM clicks WHERE short_url_id = short_urls.id) < max_clicks)
+	`, c.ShortURLID, c.Referrer, c.UserAgent, c.IPHash, (*NullTime)(&c.CreatedAt), c.ShortURLID)
+	if err != nil {
+		return err
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return suss.Errorf(suss.ECONFLICT, "Short url has reached its maximum number of clicks.")
+	}
+
+	// Read back new click ID into caller argument.
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+
+	return nil
+}
+
+func findClicks(ctx context.Context, tx *Tx, filter suss.ClickFilter) ([]*suss.Click, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ShortURLID; v != nil {
+		where, args = append(where, "short_url_id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, short_url_id, referrer, user_agent, ip_hash, created_at, COUNT(*) OVER()
+		FROM clicks
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	clicks := make([]*suss.Click, 0)
+	for rows.Next() {
+		var click suss.Click
+		if err := rows.Scan(
+			&click.ID,
+			&click.ShortURLID,
+			&click.Referrer,
+			&click.UserAgent,
+			&click.IPHash,
+			(*NullTime)(&click.CreatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		clicks = append(clicks, &click)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return clicks, n, nil
+}
+
+func findClickStats(ctx context.Context, tx *Tx, shortURLID int) (*suss.ClickStats, error) {
+	var stats suss.ClickStats
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT ip_hash), MAX(created_at)
+		FROM clicks
+		WHERE short_url_id = ?
+	`, shortURLID).Scan(
+		&stats.Total,
+		&stats.Unique,
+		(*NullTime)(&stats.LastClickedAt),
+	); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// truncateString returns s cut to at most n bytes, without splitting a
+// multi-byte character.
+func truncateString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
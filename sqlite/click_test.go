@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestClickService_Create(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewClickService(db)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+
+		for _, ipHash := range []string{"a", "a", "b"} {
+			if err := s.Create(context.Background(), &suss.Click{ShortURLID: shortUrl.ID, Referrer: "https://ref.example/", IPHash: ipHash}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if stats, err := s.FindClickStats(context.Background(), shortUrl.ID); err != nil {
+			t.Fatal(err)
+		} else if stats.Total != 3 || stats.Unique != 2 {
+			t.Fatalf("Total=%d, Unique=%d", stats.Total, stats.Unique)
+		} else if stats.LastClickedAt.IsZero() {
+			t.Fatal("expected LastClickedAt")
+		}
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewClickService(db)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+
+		// headers are sent by the visitor and may be of any size
+		if err := s.Create(context.Background(), &suss.Click{
+			ShortURLID: shortUrl.ID,
+			Referrer:   "https://ref.example/" + strings.Repeat("a", 10000),
+			UserAgent:  strings.Repeat("é", 10000),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if clicks, _, err := s.FindClicks(context.Background(), suss.ClickFilter{ShortURLID: &shortUrl.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(clicks) != 1 {
+			t.Fatalf("len(clicks)=%d", len(clicks))
+		} else if n := len(clicks[0].Referrer); n != suss.MaxClickFieldLength {
+			t.Fatalf("len(Referrer)=%d", n)
+		} else if n := len(clicks[0].UserAgent); n > suss.MaxClickFieldLength {
+			t.Fatalf("len(UserAgent)=%d", n)
+		} else if !utf8.ValidString(clicks[0].UserAgent) {
+			t.Fatalf("invalid UserAgent: %q", clicks[0].UserAgent)
+		}
+	})
+
+	t.Run("ErrShortURLRequired", func(t *testing.T) {
+		db := MustOpenDB(t)
+		if err := NewClickService(db).Create(context.Background(), &suss.Click{}); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type DomainRuleService struct {
+	db *DB
+}
+
+func NewDomainRuleService(db *DB) *DomainRuleService {
+	return &DomainRuleService{
+		db: db,
+	}
+}
+
+func (s *DomainRuleService) FindDomainRules(ctx context.Context, filter suss.DomainRuleFilter) ([]*suss.DomainRule, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findDomainRules(ctx, tx, filter)
+}
+
+func (s *DomainRuleService) Create(ctx context.Context, rule *suss.DomainRule) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := domainRuleCreate(ctx, tx, rule); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *DomainRuleService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM domain_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &suss.Error{Code: suss.ENOTFOUND, Message: "Domain rule not found."}
+	}
+
+	return tx.Commit()
+}
+
+func domainRuleCreate(ctx context.Context, tx *Tx, r *suss.DomainRule) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	// set created at
+	r.CreatedAt = tx.now
+
+	// validate the rule
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO domain_rules (pattern, action, created_at)
+		VALUES (?, ?, ?)
+	`, r.Pattern, r.Action, (*NullTime)(&r.CreatedAt))
+	if isUniqueConstraintError(err) {
+		return suss.Errorf(suss.ECONFLICT, "A rule for %s already exists.", r.Pattern)
+	} else if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	r.ID = int(id)
+
+	return nil
+}
+
+// checkDomainRules returns an error if the domain rules do not accept short
+// urls to host.
+func checkDomainRules(ctx context.Context, tx *Tx, host string) error {
+	rules, _, err := findDomainRules(ctx, tx, suss.DomainRuleFilter{})
+	if err != nil {
+		return err
+	} else if !suss.AllowsHost(rules, host) {
+		return suss.Errorf(suss.EINVALID, "Links to %s are not allowed.", host)
+	}
+	return nil
+}
+
+func findDomainRules(ctx context.Context, tx *Tx, filter suss.DomainRuleFilter) ([]*suss.DomainRule, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.Action; v != nil {
+		where, args = append(where, "action = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, pattern, action, created_at, COUNT(*) OVER()
+		FROM domain_rules
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY pattern ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	rules := make([]*suss.DomainRule, 0)
+	for rows.Next() {
+		var rule suss.DomainRule
+		if err := rows.Scan(
+			&rule.ID,
+			&rule.Pattern,
+			&rule.Action,
+			(*NullTime)(&rule.CreatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		rules = append(rules, &rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return rules, n, nil
+}
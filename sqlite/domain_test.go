@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// MustCreateDomainRule creates a domain rule as an administrator.
+func MustCreateDomainRule(tb testing.TB, db *DB, pattern, action string) *suss.DomainRule {
+	tb.Helper()
+	rule := &suss.DomainRule{Pattern: pattern, Action: action}
+	ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+	if err := NewDomainRuleService(db).Create(ctx, rule); err != nil {
+		tb.Fatal(err)
+	}
+	return rule
+}
+
+func TestDomainRuleService_Create(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		for pattern, want := range map[string]string{
+			" *.Example.COM. ": "*.example.com",
+			"bücher.example":   "xn--bcher-kva.example",
+			"192.0.2.1":        "192.0.2.1",
+		} {
+			if rule := MustCreateDomainRule(t, db, pattern, suss.DomainRuleBlock); rule.Pattern != want {
+				t.Fatalf("%q: Pattern=%q, want %q", pattern, rule.Pattern, want)
+			}
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		db := MustOpenDB(t)
+		ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+		for _, rule := range []*suss.DomainRule{
+			{Pattern: "example.com", Action: "deny"},
+			{Pattern: "", Action: suss.DomainRuleBlock},
+			{Pattern: "*.", Action: suss.DomainRuleBlock},
+			{Pattern: "ex*ample.com", Action: suss.DomainRuleBlock},
+			{Pattern: "*.192.0.2.1", Action: suss.DomainRuleBlock},
+		} {
+			if err := NewDomainRuleService(db).Create(ctx, rule); suss.ErrorCode(err) != suss.EINVALID {
+				t.Fatalf("%q: unexpected error: %v", rule.Pattern, err)
+			}
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		db := MustOpenDB(t)
+		_, ctx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if err := NewDomainRuleService(db).Create(ctx, &suss.DomainRule{Pattern: "example.com", Action: suss.DomainRuleBlock}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestShortURLService_Create_DomainRules(t *testing.T) {
+	// create returns the error code of creating a short url to longURL.
+	create := func(db *DB, longURL string) string {
+		return suss.ErrorCode(NewShortURLService(db).Create(context.Background(), &suss.ShortURL{LongURL: longURL}))
+	}
+
+	t.Run("Block", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustCreateDomainRule(t, db, "*.phish.example", suss.DomainRuleBlock)
+
+		// wildcards match the domain itself and every subdomain
+		for longURL, want := range map[string]string{
+			"https://phish.example/":          suss.EINVALID,
+			"https://login.phish.example/":    suss.EINVALID,
+			"https://A.B.PHISH.EXAMPLE:8443/": suss.EINVALID,
+			"https://notphish.example/":       "",
+			"https://phish.example.com/":      "",
+		} {
+			if got := create(db, longURL); got != want {
+				t.Fatalf("%s: code=%q, want %q", longURL, got, want)
+			}
+		}
+	})
+
+	t.Run("Allow", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustCreateDomainRule(t, db, "*.example.com", suss.DomainRuleAllow)
+		MustCreateDomainRule(t, db, "untrusted.example.com", suss.DomainRuleBlock)
+
+		// once any host is allowed, only allowed hosts are accepted, and
+		// blocked hosts win over allowed ones
+		for longURL, want := range map[string]string{
+			"https://example.com/":           "",
+			"https://docs.example.com/":      "",
+			"https://example.org/":           suss.EINVALID,
+			"https://untrusted.example.com/": suss.EINVALID,
+		} {
+			if got := create(db, longURL); got != want {
+				t.Fatalf("%s: code=%q, want %q", longURL, got, want)
+			}
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+		MustCreateDomainRule(t, db, "phish.example", suss.DomainRuleBlock)
+
+		longURL := "https://phish.example/"
+		if _, err := NewShortURLService(db).Update(context.Background(), shortUrl.ID, suss.ShortURLUpdate{LongURL: &longURL}); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
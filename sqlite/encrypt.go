@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks an encrypted value. It is followed by the id of the
+// key used for encryption and the base64 encoded nonce & ciphertext:
+//
+//	enc1:<key id>:<base64(nonce || ciphertext)>
+const encryptedPrefix = "enc1:"
+
+// encryptionKey is an AEAD cipher derived from a configured encryption key.
+type encryptionKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// newEncryptionKey derives an AES-256-GCM key from key. The derived key is
+// dedicated to database values so the same key can safely be used elsewhere.
+func newEncryptionKey(key []byte) (*encryptionKey, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("encryption key required")
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("suss sqlite encryption"))
+	derived := mac.Sum(nil)
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	id := sha256.Sum256(derived)
+	return &encryptionKey{
+		id:   hex.EncodeToString(id[:4]),
+		aead: aead,
+	}, nil
+}
+
+// openEncryptionKeys prepares the current and previous encryption keys.
+func (db *DB) openEncryptionKeys() (err error) {
+	if db.encryptionKey, err = newEncryptionKey(db.EncryptionKey); err != nil {
+		return err
+	}
+
+	db.encryptionKeys = map[string]*encryptionKey{db.encryptionKey.id: db.encryptionKey}
+	for _, v := range db.PreviousEncryptionKeys {
+		key, err := newEncryptionKey(v)
+		if err != nil {
+			return err
+		}
+		db.encryptionKeys[key.id] = key
+	}
+
+	return nil
+}
+
+// encrypt encrypts plaintext with the current encryption key.
+func (db *DB) encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, db.encryptionKey.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	buf := db.encryptionKey.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + db.encryptionKey.id + ":" + base64.RawStdEncoding.EncodeToString(buf), nil
+}
+
+// decrypt decrypts a value returned by encrypt using the key it was encrypted
+// with. Values without the encrypted prefix are returned as-is.
+func (db *DB) decrypt(v string) (string, error) {
+	if !strings.HasPrefix(v, encryptedPrefix) {
+		return v, nil
+	}
+
+	id, data, ok := strings.Cut(strings.TrimPrefix(v, encryptedPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	key, ok := db.encryptionKeys[id]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key: id=%s", id)
+	}
+
+	buf, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	} else if len(buf) < key.aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	nonce, ciphertext := buf[:key.aead.NonceSize()], buf[key.aead.NonceSize():]
+	plaintext, err := key.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Reencrypt encrypts every long url and email which is not yet encrypted with
+// the current encryption key and returns the number of values updated. It is
+// used after rotating keys, once done previous keys can be removed.
+func (db *DB) Reencrypt(ctx context.Context) (n int, err error) {
+	current := encryptedPrefix + db.encryptionKey.id + ":%"
+
+	for _, v := range []struct{ table, column, where string }{
+		{"short_urls", "long_url", `long_url NOT LIKE ?`},
+		{"short_urls", "email", `email != '' AND email NOT LIKE ?`},
+		{"users", "email", `email NOT LIKE ?`},
+		{"audit_events", "before_value", `before_value != '' AND before_value NOT LIKE ?`},
+		{"audit_events", "after_value", `after_value != '' AND after_value NOT LIKE ?`},
+	} {
+		updated, err := db.encryptColumn(ctx, v.table, v.column, v.where, current)
+		if n += updated; err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// encryptColumn re-encrypts a column of the rows matching the where clause in
+// batches, so large tables do not hold a single long write transaction. table
+// and column must be trusted names.
+func (db *DB) encryptColumn(ctx context.Context, table, column, where string, args ...interface{}) (n int, err error) {
+	const batchSize = 500
+
+	for {
+		updated, err := db.encryptColumnBatch(ctx, table, column, batchSize, where, args...)
+		if n += updated; err != nil {
+			return n, err
+		} else if updated < batchSize {
+			return n, nil
+		}
+	}
+}
+
+func (db *DB) encryptColumnBatch(ctx context.Context, table, column string, limit int, where string, args ...interface{}) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, `+column+`
+		FROM `+table+`
+		WHERE `+where+`
+		ORDER BY id
+		LIMIT `+fmt.Sprint(limit), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	values := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return 0, err
+		}
+		values[id] = value
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, value := range values {
+		plaintext, err := db.decrypt(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s %d: %w", table, id, err)
+		}
+		encrypted, err := db.encrypt(plaintext)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE `+table+` SET `+column+` = ? WHERE id = ?`, encrypted, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(values), tx.Commit()
+}
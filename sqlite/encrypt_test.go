@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestDB_Encrypt(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		encrypted, err := db.encrypt("https://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		} else if !strings.HasPrefix(encrypted, encryptedPrefix) || strings.Contains(encrypted, "example") {
+			t.Fatalf("unexpected value: %s", encrypted)
+		}
+
+		// every value gets its own nonce
+		if other, err := db.encrypt("https://example.com/"); err != nil {
+			t.Fatal(err)
+		} else if other == encrypted {
+			t.Fatal("expected distinct ciphertexts")
+		}
+
+		if plaintext, err := db.decrypt(encrypted); err != nil {
+			t.Fatal(err)
+		} else if plaintext != "https://example.com/" {
+			t.Fatalf("plaintext=%q", plaintext)
+		}
+	})
+
+	t.Run("Plaintext", func(t *testing.T) {
+		// values stored before encryption are returned as-is
+		if plaintext, err := MustOpenDB(t).decrypt("https://example.com/"); err != nil {
+			t.Fatal(err)
+		} else if plaintext != "https://example.com/" {
+			t.Fatalf("plaintext=%q", plaintext)
+		}
+	})
+
+	t.Run("LongURL", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/signed?token=abc"})
+
+		var longURL string
+		if err := db.db.QueryRow(`SELECT long_url FROM short_urls WHERE id = ?`, shortUrl.ID).Scan(&longURL); err != nil {
+			t.Fatal(err)
+		} else if !strings.HasPrefix(longURL, encryptedPrefix) || strings.Contains(longURL, "token") {
+			t.Fatalf("long_url=%q", longURL)
+		}
+	})
+
+	t.Run("ErrTampered", func(t *testing.T) {
+		db := MustOpenDB(t)
+		encrypted, err := db.encrypt("https://example.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// change a byte of the ciphertext, short of the final base64 padding bits
+		buf := []byte(encrypted)
+		if i := len(buf) - 2; buf[i] == 'A' {
+			buf[i] = 'B'
+		} else {
+			buf[i] = 'A'
+		}
+		if _, err := db.decrypt(string(buf)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestDB_Reencrypt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	oldKey, newKey := []byte("old key"), []byte("new key")
+
+	// open opens the database at path with key and previous keys.
+	open := func(key []byte, previousKeys ...[]byte) *DB {
+		t.Helper()
+		db := NewDB(path)
+		db.HashKey = []byte("hash key")
+		db.EncryptionKey, db.PreviousEncryptionKeys = key, previousKeys
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+
+	db := open(oldKey)
+	MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{Slug: "rotate", LongURL: "https://example.com/", Email: "susy@example.com"})
+	db.Close()
+
+	// previous keys still decrypt values until they are re-encrypted
+	db = open(newKey, oldKey)
+	if shortUrl, err := NewShortURLService(db).FindDialBySlug(context.Background(), "rotate"); err != nil {
+		t.Fatal(err)
+	} else if shortUrl.LongURL != "https://example.com/" {
+		t.Fatalf("LongURL=%q", shortUrl.LongURL)
+	}
+	if n, err := db.Reencrypt(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n == 0 {
+		t.Fatal("expected values to be re-encrypted")
+	}
+	if n, err := db.Reencrypt(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("n=%d, want no more values to re-encrypt", n)
+	}
+	db.Close()
+
+	// the previous key is no longer needed
+	db = open(newKey)
+	defer db.Close()
+	if shortUrl, err := NewShortURLService(db).FindDialBySlug(context.Background(), "rotate"); err != nil {
+		t.Fatal(err)
+	} else if shortUrl.LongURL != "https://example.com/" || shortUrl.Email != "susy@example.com" {
+		t.Fatalf("LongURL=%q, Email=%q", shortUrl.LongURL, shortUrl.Email)
+	}
+}
+
+func TestDB_Open_ErrUnknownEncryptionKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db := NewDB(path)
+	db.HashKey, db.EncryptionKey = []byte("hash key"), []byte("old key")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{Slug: "rotate", LongURL: "https://example.com/"})
+	db.Close()
+
+	// values encrypted with a key which is no longer configured cannot be read
+	db = NewDB(path)
+	db.HashKey, db.EncryptionKey = []byte("hash key"), []byte("new key")
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := NewShortURLService(db).FindDialBySlug(context.Background(), "rotate"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type IdentityService struct {
+	db *DB
+}
+
+func NewIdentityService(db *DB) *IdentityService {
+	return &IdentityService{
+		db: db,
+	}
+}
+
+func (s *IdentityService) CreateIdentity(ctx context.Context, identity *suss.Identity) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := identityCreate(ctx, tx, identity); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func identityCreate(ctx context.Context, tx *Tx, i *suss.Identity) error {
+	if i.User == nil {
+		return suss.Errorf(suss.EINVALID, "User required.")
+	}
+
+	// sign in known identities as their linked user
+	if other, err := findIdentityBySubject(ctx, tx, i.Issuer, i.Subject); err == nil {
+		*i = *other
+		i.User, err = findUserByID(ctx, tx, i.UserID)
+		return err
+	} else if suss.ErrorCode(err) != suss.ENOTFOUND {
+		return err
+	}
+
+	// link to the signed in user, otherwise provision a new user. accounts
+	// are never linked by email alone, as that would let whoever controls
+	// the email at the provider take over an existing account
+	if user := suss.UserFromContext(ctx); user != nil && user.ID != 0 {
+		i.User = user
+	} else if users, _, err := findUsers(ctx, tx, suss.UserFilter{Email: &i.User.Email}); err != nil {
+		return err
+	} else if len(users) > 0 {
+		return suss.Errorf(suss.ECONFLICT, "An account with this email already exists. Sign in to it first, then sign in with single sign-on again to link it.")
+	} else if err := userCreate(ctx, tx, i.User); err != nil {
+		return err
+	}
+	i.UserID = i.User.ID
+
+	// set created at
+	i.CreatedAt = tx.now
+
+	// validate the identity
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO identities (user_id, issuer, subject, created_at)
+		VALUES (?, ?, ?, ?)
+	`, i.UserID, i.Issuer, i.Subject, (*NullTime)(&i.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	i.ID = int(id)
+
+	return nil
+}
+
+func findIdentityBySubject(ctx context.Context, tx *Tx, issuer, subject string) (*suss.Identity, error) {
+	var identity suss.Identity
+	if err := tx.QueryRowContext(ctx, `
+		SELECT id, user_id, issuer, subject, created_at
+		FROM identities
+		WHERE issuer = ? AND subject = ?
+	`, issuer, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		(*NullTime)(&identity.CreatedAt),
+	); err == sql.ErrNoRows {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Identity not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
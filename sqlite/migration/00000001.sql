@@ -0,0 +1,10 @@
+CREATE TABLE clicks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	short_url_id INTEGER NOT NULL REFERENCES short_urls (id) ON DELETE CASCADE,
+	referrer TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	ip_hash TEXT NOT NULL,
+	created_at    TEXT NOT NULL
+);
+
+CREATE INDEX clicks_short_url_id_idx ON clicks (short_url_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE short_urls ADD COLUMN expires_at TEXT;
+ALTER TABLE short_urls ADD COLUMN max_clicks INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE short_urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+ALTER TABLE short_urls ADD COLUMN long_url_host TEXT NOT NULL DEFAULT '';
+
+-- backfill the destination host of existing rows
+UPDATE short_urls SET long_url_host = (
+	SELECT lower(CASE WHEN h LIKE '[%' THEN substr(h, 2, instr(h, ']') - 2) ELSE substr(h, 1, instr(h || ':', ':') - 1) END)
+	FROM (SELECT substr(hp, instr(hp, '@') + 1) AS h
+	FROM (SELECT substr(r, 1, instr(r, '/') - 1) AS hp
+	FROM (SELECT replace(replace(substr(long_url, instr(long_url, '://') + 3), '?', '/'), '#', '/') || '/' AS r)))
+);
+
+CREATE INDEX short_urls_long_url_host_idx ON short_urls (long_url_host);
+CREATE INDEX short_urls_created_at_idx ON short_urls (created_at);
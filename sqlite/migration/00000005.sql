@@ -0,0 +1,3 @@
+-- secret keys are stored as a keyed hash, existing plaintext values in
+-- secret_key are hashed and cleared by DB.Open on startup
+ALTER TABLE short_urls ADD COLUMN secret_key_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- optional owner email used to recover the manage link, encrypted like long_url
+ALTER TABLE short_urls ADD COLUMN email TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,25 @@
+-- emails are encrypted like long_url, email_hash is a keyed hash of the
+-- normalized email used to look users up and keep emails unique
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	email_hash TEXT UNIQUE NOT NULL,
+	password_hash TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL
+);
+
+CREATE TABLE sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	token_hash TEXT UNIQUE NOT NULL,
+	expires_at TEXT NOT NULL,
+	created_at    TEXT NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+ALTER TABLE short_urls ADD COLUMN owner_id INTEGER REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX short_urls_owner_id_idx ON short_urls (owner_id, created_at);
@@ -0,0 +1,23 @@
+CREATE TABLE workspaces (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL
+);
+
+CREATE TABLE workspace_members (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workspace_id INTEGER NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+
+	UNIQUE (workspace_id, user_id)
+);
+
+CREATE INDEX workspace_members_user_id_idx ON workspace_members (user_id);
+
+-- links of a deleted workspace go back to the personal links of their owner
+ALTER TABLE short_urls ADD COLUMN workspace_id INTEGER REFERENCES workspaces (id) ON DELETE SET NULL;
+
+CREATE INDEX short_urls_workspace_id_idx ON short_urls (workspace_id, created_at);
@@ -0,0 +1,13 @@
+-- scopes are stored space separated, e.g. "links:read links:write"
+CREATE TABLE api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	key_hash TEXT UNIQUE NOT NULL,
+	prefix TEXT NOT NULL,
+	scopes TEXT NOT NULL,
+	last_used_at TEXT,
+	created_at    TEXT NOT NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
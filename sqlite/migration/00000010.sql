@@ -0,0 +1,11 @@
+CREATE TABLE identities (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+
+	UNIQUE (issuer, subject)
+);
+
+CREATE INDEX identities_user_id_idx ON identities (user_id);
@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0;
+
+-- patterns are lowercase host names, "*.example.com" also matches subdomains
+CREATE TABLE domain_rules (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	pattern TEXT UNIQUE NOT NULL,
+	action TEXT NOT NULL,
+	created_at    TEXT NOT NULL
+);
@@ -0,0 +1,2 @@
+-- threat type the destination was flagged for by the url checker, if any
+ALTER TABLE short_urls ADD COLUMN threat TEXT NOT NULL DEFAULT '';
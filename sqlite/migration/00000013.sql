@@ -0,0 +1,15 @@
+ALTER TABLE short_urls ADD COLUMN disabled_at TEXT;
+
+CREATE TABLE abuse_reports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	short_url_id INTEGER NOT NULL REFERENCES short_urls (id) ON DELETE CASCADE,
+	reason TEXT NOT NULL,
+	comment TEXT NOT NULL,
+	ip_hash TEXT NOT NULL,
+	status TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	resolved_at TEXT
+);
+
+CREATE INDEX abuse_reports_status_idx ON abuse_reports (status);
+CREATE INDEX abuse_reports_short_url_id_idx ON abuse_reports (short_url_id);
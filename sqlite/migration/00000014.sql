@@ -0,0 +1,19 @@
+-- short urls are not referenced so their events outlive them. before_value
+-- and after_value hold encrypted json snapshots, empty when there is none.
+CREATE TABLE audit_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL,
+	actor_type TEXT NOT NULL,
+	actor_user_id INTEGER,
+	actor_api_key_id INTEGER,
+	actor_ip_hash TEXT NOT NULL,
+	short_url_id INTEGER NOT NULL,
+	slug TEXT NOT NULL,
+	before_value TEXT NOT NULL,
+	after_value TEXT NOT NULL,
+	request_id TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX audit_events_short_url_id_idx ON audit_events (short_url_id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
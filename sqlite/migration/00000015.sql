@@ -0,0 +1,4 @@
+-- single-use token emailed to recover the manage link, stored as a keyed hash
+-- like the secret key. the secret key is only rotated once it is redeemed.
+ALTER TABLE short_urls ADD COLUMN recovery_token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE short_urls ADD COLUMN recovery_token_expires_at TEXT;
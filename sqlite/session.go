@@ -0,0 +1,138 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type SessionService struct {
+	db *DB
+}
+
+func NewSessionService(db *DB) *SessionService {
+	return &SessionService{
+		db: db,
+	}
+}
+
+func (s *SessionService) FindSessionByToken(ctx context.Context, token string) (*suss.Session, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := findSessionByToken(ctx, tx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.User, err = findUserByID(ctx, tx, session.UserID); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SessionService) Create(ctx context.Context, session *suss.Session) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := sessionCreate(ctx, tx, session); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SessionService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func sessionCreate(ctx context.Context, tx *Tx, s *suss.Session) error {
+	// verify the user exists
+	user, err := findUserByID(ctx, tx, s.UserID)
+	if err != nil {
+		return err
+	}
+	s.User = user
+
+	// generate a token, only its hash is stored
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	s.Token = base64.RawURLEncoding.EncodeToString(buf)
+	s.TokenHash = tx.db.hash(s.Token)
+
+	// set created at and expiry
+	s.CreatedAt = tx.now
+	s.ExpiresAt = s.CreatedAt.Add(suss.SessionDuration)
+
+	// clean up expired sessions while we are writing anyway
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= ?`, (*NullTime)(&tx.now)); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO sessions (user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`, s.UserID, s.TokenHash, (*NullTime)(&s.ExpiresAt), (*NullTime)(&s.CreatedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	s.ID = int(id)
+
+	return nil
+}
+
+func findSessionByToken(ctx context.Context, tx *Tx, token string) (*suss.Session, error) {
+	if token == "" {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Session not found."}
+	}
+
+	var session suss.Session
+	if err := tx.QueryRowContext(ctx, `
+		SELECT id, user_id, token_hash, expires_at, created_at
+		FROM sessions
+		WHERE token_hash = ?
+	`, tx.db.hash(token)).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.TokenHash,
+		(*NullTime)(&session.ExpiresAt),
+		(*NullTime)(&session.CreatedAt),
+	); err == sql.ErrNoRows {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Session not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if session.IsExpired(tx.now) {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Session not found."}
+	}
+
+	return &session, nil
+}
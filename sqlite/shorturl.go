@@ -3,10 +3,13 @@ package sqlite
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"math/big"
 	"strings"
+	"time"
 
 	"github.com/heyjorgedev/suss"
 )
@@ -42,6 +45,19 @@ func (s *ShortURLService) FindShortUrls(ctx context.Context, filter suss.ShortUR
 	}
 	defer tx.Rollback()
 
+	// scope the filter to the short urls the current user can see, workspace
+	// membership is checked by findShortUrls
+	if requireAdmin(ctx) != nil {
+		user := suss.UserFromContext(ctx)
+		if user == nil || user.ID == 0 {
+			return nil, 0, suss.Errorf(suss.EUNAUTHORIZED, "You must be signed in to list short urls.")
+		}
+		if filter.WorkspaceID == nil || *filter.WorkspaceID == 0 {
+			noWorkspace := 0
+			filter.OwnerID, filter.WorkspaceID = &user.ID, &noWorkspace
+		}
+	}
+
 	// Fetch list of matching dial objects.
 	shortUrls, n, err := findShortUrls(ctx, tx, filter)
 	if err != nil {
@@ -66,13 +82,170 @@ func (s *ShortURLService) FindDialBySlug(ctx context.Context, slug string) (*sus
 	return shortUrl, nil
 }
 
-func shortUrlCreate(ctx context.Context, tx *Tx, s *suss.ShortURL) error {
-	// generate a unique slug
-	slug, err := shortUrlGenerateSlug(tx)
+func (s *ShortURLService) FindShortUrlBySecretKey(ctx context.Context, slug, secretKey string) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findShortUrlBySecretKey(ctx, tx, slug, secretKey)
+}
+
+func (s *ShortURLService) Update(ctx context.Context, id int, upd suss.ShortURLUpdate) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortUrl, err := shortUrlUpdate(ctx, tx, id, upd)
+	if err != nil {
+		return shortUrl, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return shortUrl, err
+	}
+
+	return shortUrl, nil
+}
+
+func (s *ShortURLService) RotateSecretKey(ctx context.Context, id int) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortUrl, err := shortUrlRotateSecretKey(ctx, tx, id)
+	if err != nil {
+		return shortUrl, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return shortUrl, err
+	}
+
+	return shortUrl, nil
+}
+
+func (s *ShortURLService) CreateRecoveryToken(ctx context.Context, slug, email string) (*suss.ShortURL, string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer tx.Rollback()
+
+	shortUrl, token, err := shortUrlCreateRecoveryToken(ctx, tx, slug, email)
+	if err != nil {
+		return shortUrl, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return shortUrl, "", err
+	}
+
+	return shortUrl, token, nil
+}
+
+func (s *ShortURLService) RecoverShortUrl(ctx context.Context, slug, token string) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortUrl, err := shortUrlRecover(ctx, tx, slug, token)
+	if err != nil {
+		return shortUrl, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return shortUrl, err
+	}
+
+	return shortUrl, nil
+}
+
+func (s *ShortURLService) Disable(ctx context.Context, id int) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortUrl, err := shortUrlDisable(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return shortUrl, tx.Commit()
+}
+
+func (s *ShortURLService) Enable(ctx context.Context, id int) (*suss.ShortURL, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	shortUrl, err := shortUrlEnable(ctx, tx, id)
 	if err != nil {
+		return nil, err
+	}
+
+	return shortUrl, tx.Commit()
+}
+
+func (s *ShortURLService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := shortUrlDelete(ctx, tx, id); err != nil {
 		return err
 	}
-	s.Slug = slug
+
+	return tx.Commit()
+}
+
+func (s *ShortURLService) BulkApply(ctx context.Context, action string, ids []int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := shortUrlBulkApply(ctx, tx, action, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+func shortUrlCreate(ctx context.Context, tx *Tx, s *suss.ShortURL) error {
+	// generate a unique slug unless the caller chose one
+	if s.Slug == "" {
+		slug, err := shortUrlGenerateSlug(tx)
+		if err != nil {
+			return err
+		}
+		s.Slug = slug
+	}
+
+	// short urls created while signed in belong to the user
+	s.OwnerID = suss.UserIDFromContext(ctx)
+
+	// only editors can add short urls to a workspace
+	if s.WorkspaceID != 0 {
+		if _, err := requireWorkspaceRole(ctx, tx, s.WorkspaceID, suss.WorkspaceRoleEditor); err != nil {
+			return err
+		}
+	}
 
 	// generate secret key
 	secretKey, err := shortUrlGenerateSecretKey(tx)
@@ -80,6 +253,15 @@ func shortUrlCreate(ctx context.Context, tx *Tx, s *suss.ShortURL) error {
 		return err
 	}
 	s.SecretKey = secretKey
+	s.SecretKeyHash = tx.db.hash(secretKey)
+
+	// hash the password, the plaintext is never stored
+	if s.Password != "" {
+		if s.PasswordHash, err = suss.HashPassword(s.Password); err != nil {
+			return err
+		}
+		s.Password = ""
+	}
 
 	// set created and updated at
 	s.CreatedAt = tx.now
@@ -88,16 +270,34 @@ func shortUrlCreate(ctx context.Context, tx *Tx, s *suss.ShortURL) error {
 	// validate the short url
 	if err := s.Validate(); err != nil {
 		return err
+	} else if err := suss.CheckRequestHost(ctx, s.Host()); err != nil {
+		return err
+	} else if err := checkDomainRules(ctx, tx, s.Host()); err != nil {
+		return err
+	} else if err := checkURLThreat(ctx, tx, s.LongURL); err != nil {
+		return err
 	}
 
-	result, err := tx.ExecContext(ctx, `
-		INSERT INTO short_urls (slug, long_url, secret_key, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, s.Slug, s.LongURL, s.SecretKey, (*NullTime)(&s.CreatedAt), (*NullTime)(&s.UpdatedAt))
+	// encrypt the destination, only its host is stored in plaintext
+	longURL, err := tx.db.encrypt(s.LongURL)
+	if err != nil {
+		return err
+	}
+	email, err := encryptEmail(tx, s.Email)
 	if err != nil {
 		return err
 	}
 
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO short_urls (slug, long_url, long_url_host, secret_key, secret_key_hash, expires_at, max_clicks, password_hash, email, owner_id, workspace_id, created_at, updated_at)
+		VALUES (?, ?, ?, '', ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.Slug, longURL, s.Host(), s.SecretKeyHash, (*NullTime)(&s.ExpiresAt), s.MaxClicks, s.PasswordHash, email, sql.NullInt64{Int64: int64(s.OwnerID), Valid: s.OwnerID != 0}, sql.NullInt64{Int64: int64(s.WorkspaceID), Valid: s.WorkspaceID != 0}, (*NullTime)(&s.CreatedAt), (*NullTime)(&s.UpdatedAt))
+	if isUniqueConstraintError(err) {
+		return suss.Errorf(suss.ECONFLICT, "Slug %q is already taken.", s.Slug)
+	} else if err != nil {
+		return err
+	}
+
 	// Read back new dial ID into caller argument.
 	id, err := result.LastInsertId()
 	if err != nil {
@@ -105,7 +305,316 @@ func shortUrlCreate(ctx context.Context, tx *Tx, s *suss.ShortURL) error {
 	}
 	s.ID = int(id)
 
-	return nil
+	return auditEventCreate(ctx, tx, suss.AuditShortURLCreate, nil, s)
+}
+
+func shortUrlUpdate(ctx context.Context, tx *Tx, id int, upd suss.ShortURLUpdate) (*suss.ShortURL, error) {
+	// fetch the current short url
+	shortUrl, err := findShortUrlByID(ctx, tx, id)
+	if err != nil {
+		return shortUrl, err
+	} else if err := requireShortUrlEditor(ctx, tx, shortUrl); err != nil {
+		return shortUrl, err
+	}
+	before := *shortUrl
+
+	// update fields
+	if v := upd.LongURL; v != nil {
+		shortUrl.LongURL = *v
+	}
+	if v := upd.ExpiresAt; v != nil {
+		shortUrl.ExpiresAt = *v
+	}
+	if v := upd.MaxClicks; v != nil {
+		shortUrl.MaxClicks = *v
+	}
+	if v := upd.Password; v != nil {
+		shortUrl.PasswordHash = ""
+		if *v != "" {
+			if shortUrl.PasswordHash, err = suss.HashPassword(*v); err != nil {
+				return shortUrl, err
+			}
+		}
+	}
+	if v := upd.Email; v != nil {
+		shortUrl.Email = *v
+	}
+
+	// set last updated at
+	shortUrl.UpdatedAt = tx.now
+
+	// validate the short url, a new expiration date must be in the future and
+	// a new destination must pass the domain rules
+	if err := shortUrl.Validate(); err != nil {
+		return shortUrl, err
+	} else if v := shortUrl.ExpiresAt; !v.Equal(before.ExpiresAt) && !v.IsZero() && !v.After(tx.now) {
+		return shortUrl, suss.Errorf(suss.EINVALID, "Expiration date must be in the future.")
+	} else if upd.LongURL != nil {
+		if err := suss.CheckRequestHost(ctx, shortUrl.Host()); err != nil {
+			return shortUrl, err
+		} else if err := checkDomainRules(ctx, tx, shortUrl.Host()); err != nil {
+			return shortUrl, err
+		} else if err := checkURLThreat(ctx, tx, shortUrl.LongURL); err != nil {
+			return shortUrl, err
+		}
+		shortUrl.Threat = ""
+	}
+
+	longURL, err := tx.db.encrypt(shortUrl.LongURL)
+	if err != nil {
+		return shortUrl, err
+	}
+	email, err := encryptEmail(tx, shortUrl.Email)
+	if err != nil {
+		return shortUrl, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE short_urls
+		SET long_url = ?,
+		    long_url_host = ?,
+		    expires_at = ?,
+		    max_clicks = ?,
+		    password_hash = ?,
+		    email = ?,
+		    threat = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, longURL, shortUrl.Host(), (*NullTime)(&shortUrl.ExpiresAt), shortUrl.MaxClicks, shortUrl.PasswordHash, email, shortUrl.Threat, (*NullTime)(&shortUrl.UpdatedAt), id); err != nil {
+		return shortUrl, err
+	}
+
+	if err := auditEventCreate(ctx, tx, suss.AuditShortURLUpdate, &before, shortUrl); err != nil {
+		return shortUrl, err
+	}
+
+	return shortUrl, nil
+}
+
+func shortUrlRotateSecretKey(ctx context.Context, tx *Tx, id int) (*suss.ShortURL, error) {
+	// fetch the current short url
+	shortUrl, err := findShortUrlByID(ctx, tx, id)
+	if err != nil {
+		return shortUrl, err
+	} else if err := requireShortUrlEditor(ctx, tx, shortUrl); err != nil {
+		return shortUrl, err
+	}
+
+	// generate a new secret key, replacing the hash invalidates the old one
+	secretKey, err := shortUrlGenerateSecretKey(tx)
+	if err != nil {
+		return shortUrl, err
+	}
+	// secrets are never recorded, the action alone tells the key was rotated
+	before := *shortUrl
+	shortUrl.SecretKey = secretKey
+	shortUrl.SecretKeyHash = tx.db.hash(secretKey)
+	shortUrl.UpdatedAt = tx.now
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE short_urls
+		SET secret_key_hash = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, shortUrl.SecretKeyHash, (*NullTime)(&shortUrl.UpdatedAt), id); err != nil {
+		return shortUrl, err
+	}
+
+	if err := auditEventCreate(ctx, tx, suss.AuditShortURLRotateSecretKey, &before, shortUrl); err != nil {
+		return shortUrl, err
+	}
+
+	return shortUrl, nil
+}
+
+func shortUrlCreateRecoveryToken(ctx context.Context, tx *Tx, slug, email string) (*suss.ShortURL, string, error) {
+	email, err := suss.NormalizeEmail(email)
+	if err != nil {
+		return nil, "", err
+	}
+
+	shortUrl, err := findShortUrlBySlug(ctx, tx, slug)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// compare emails in constant time, workspace links are recovered by members
+	if shortUrl.WorkspaceID != 0 || shortUrl.Email == "" || subtle.ConstantTimeCompare([]byte(email), []byte(shortUrl.Email)) != 1 {
+		return nil, "", suss.Errorf(suss.EUNAUTHORIZED, "Email does not match.")
+	}
+
+	// tokens are random like secret keys and only their hash is stored
+	token, err := shortUrlGenerateSecretKey(tx)
+	if err != nil {
+		return nil, "", err
+	}
+	expiresAt := tx.now.Add(suss.RecoveryTokenTTL)
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE short_urls
+		SET recovery_token_hash = ?,
+		    recovery_token_expires_at = ?
+		WHERE id = ?
+	`, tx.db.hash(token), (*NullTime)(&expiresAt), shortUrl.ID); err != nil {
+		return nil, "", err
+	}
+
+	return shortUrl, token, nil
+}
+
+func shortUrlRecover(ctx context.Context, tx *Tx, slug, token string) (*suss.ShortURL, error) {
+	if slug == "" {
+		return nil, suss.Errorf(suss.EINVALID, "slug required")
+	} else if token == "" {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "This recovery link is invalid or has expired.")
+	}
+
+	// clearing the token with the same statement which checks it makes sure
+	// it can only be redeemed once
+	var id int
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE short_urls
+		SET recovery_token_hash = '',
+		    recovery_token_expires_at = NULL
+		WHERE slug = ?
+		  AND recovery_token_hash = ?
+		  AND recovery_token_expires_at > ?
+		RETURNING id
+	`, slug, tx.db.hash(token), (*NullTime)(&tx.now)).Scan(&id); errors.Is(err, sql.ErrNoRows) {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "This recovery link is invalid or has expired.")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return shortUrlRotateSecretKey(ctx, tx, id)
+}
+
+// encryptEmail encrypts an owner email. No email is stored as an empty string.
+func encryptEmail(tx *Tx, email string) (string, error) {
+	if email == "" {
+		return "", nil
+	}
+	return tx.db.encrypt(email)
+}
+
+func shortUrlDelete(ctx context.Context, tx *Tx, id int) error {
+	// verify the short url exists
+	shortUrl, err := findShortUrlByID(ctx, tx, id)
+	if err != nil {
+		return err
+	} else if err := requireShortUrlEditor(ctx, tx, shortUrl); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM short_urls WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return auditEventCreate(ctx, tx, suss.AuditShortURLDelete, shortUrl, nil)
+}
+
+// shortUrlDisable stops a short url from redirecting and resolves its open
+// abuse reports. Only administrators can disable short urls.
+func shortUrlDisable(ctx context.Context, tx *Tx, id int) (*suss.ShortURL, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	shortUrl, err := findShortUrlByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !shortUrl.IsDisabled() {
+		before := *shortUrl
+		shortUrl.DisabledAt = tx.now
+		if _, err := tx.ExecContext(ctx, `UPDATE short_urls SET disabled_at = ? WHERE id = ?`, (*NullTime)(&shortUrl.DisabledAt), id); err != nil {
+			return nil, err
+		} else if err := auditEventCreate(ctx, tx, suss.AuditShortURLDisable, &before, shortUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	// the link is gone, so every open report for it is resolved
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE abuse_reports SET status = ?, resolved_at = ?
+		WHERE short_url_id = ? AND status = ?
+	`, suss.AbuseReportDisabled, (*NullTime)(&tx.now), id, suss.AbuseReportOpen); err != nil {
+		return nil, err
+	}
+
+	return shortUrl, nil
+}
+
+// shortUrlEnable lets a disabled short url redirect again. Only administrators
+// can enable short urls.
+func shortUrlEnable(ctx context.Context, tx *Tx, id int) (*suss.ShortURL, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	shortUrl, err := findShortUrlByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if !shortUrl.IsDisabled() {
+		return shortUrl, nil
+	}
+
+	before := *shortUrl
+	shortUrl.DisabledAt = time.Time{}
+	if _, err := tx.ExecContext(ctx, `UPDATE short_urls SET disabled_at = NULL WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+
+	if err := auditEventCreate(ctx, tx, suss.AuditShortURLEnable, &before, shortUrl); err != nil {
+		return nil, err
+	}
+
+	return shortUrl, nil
+}
+
+// shortUrlBulkApply applies action to each short url, skipping the ones
+// deleted in the meantime. Only administrators can apply bulk actions.
+func shortUrlBulkApply(ctx context.Context, tx *Tx, action string, ids []int) (int, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return 0, err
+	} else if action != suss.ShortURLBulkDisable && action != suss.ShortURLBulkEnable && action != suss.ShortURLBulkDelete {
+		return 0, suss.Errorf(suss.EINVALID, "Invalid action %q.", action)
+	}
+
+	var n int
+	for _, id := range ids {
+		var err error
+		switch action {
+		case suss.ShortURLBulkDisable:
+			_, err = shortUrlDisable(ctx, tx, id)
+		case suss.ShortURLBulkEnable:
+			_, err = shortUrlEnable(ctx, tx, id)
+		case suss.ShortURLBulkDelete:
+			err = shortUrlDelete(ctx, tx, id)
+		}
+
+		if suss.ErrorCode(err) == suss.ENOTFOUND {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// requireShortUrlEditor returns an EUNAUTHORIZED error if the short url is in
+// a workspace the current user cannot edit, unless they are an administrator.
+// Other short urls are authorized by their secret key or owner before calling
+// the service.
+func requireShortUrlEditor(ctx context.Context, tx *Tx, shortUrl *suss.ShortURL) error {
+	if shortUrl.WorkspaceID == 0 || requireAdmin(ctx) == nil {
+		return nil
+	}
+	_, err := requireWorkspaceRole(ctx, tx, shortUrl.WorkspaceID, suss.WorkspaceRoleEditor)
+	return err
 }
 
 const slugAlphabet = "abcdefghijkmnopqrstuvwxyz" + "23456789" // avoid 0's and o's, 1's l's - for less ambiguity
@@ -139,8 +648,8 @@ func shortUrlGenerateSlug(tx *Tx) (string, error) {
 			return "", err
 		}
 
-		// if not exists, return it
-		if !exists {
+		// if not exists and usable, return it
+		if !exists && !suss.IsReservedSlug(slug) {
 			return slug, nil
 		}
 	}
@@ -161,14 +670,57 @@ func shortUrlGenerateSecretKey(tx *Tx) (string, error) {
 
 func findShortUrls(ctx context.Context, tx *Tx, filter suss.ShortURLFilter) ([]*suss.ShortURL, int, error) {
 	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
 	if v := filter.Slug; v != nil {
 		where, args = append(where, "slug = ?"), append(args, *v)
 	}
+	if v := filter.OwnerID; v != nil {
+		where, args = append(where, "owner_id = ?"), append(args, *v)
+	}
+	if v := filter.WorkspaceID; v != nil && *v == 0 {
+		where = append(where, "workspace_id IS NULL")
+	} else if v != nil {
+		if _, err := requireWorkspaceRole(ctx, tx, *v, suss.WorkspaceRoleViewer); err != nil {
+			return nil, 0, err
+		}
+		where, args = append(where, "workspace_id = ?"), append(args, *v)
+	}
+	if v := filter.Host; v != nil {
+		where, args = append(where, "long_url_host = ?"), append(args, strings.ToLower(*v))
+	}
+	if v := filter.CreatedAfter; v != nil {
+		where, args = append(where, "created_at >= ?"), append(args, (*NullTime)(v))
+	}
+	if v := filter.CreatedBefore; v != nil {
+		where, args = append(where, "created_at < ?"), append(args, (*NullTime)(v))
+	}
+
+	orderBy, ok := shortUrlSortOrders[filter.Sort]
+	if !ok {
+		return nil, 0, suss.Errorf(suss.EINVALID, "Invalid sort order %q.", filter.Sort)
+	}
+
+	// long urls are encrypted so a substring search cannot be done in SQL,
+	// instead a bounded number of other matches are decrypted and paginated
+	// afterwards
+	limitOffset := FormatLimitOffset(filter.Limit, filter.Offset)
+	if filter.LongURL != nil {
+		if filter.OwnerID == nil && filter.WorkspaceID == nil {
+			if err := requireAdmin(ctx); err != nil {
+				return nil, 0, suss.Errorf(suss.EUNAUTHORIZED, "Searching by long url requires an owner or workspace filter.")
+			}
+		}
+		limitOffset = FormatLimitOffset(suss.LongURLSearchLimit, 0)
+	}
 
 	rows, err := tx.QueryContext(ctx, `
-		SELECT id, slug, long_url, secret_key, created_at, updated_at, COUNT(*) OVER()
+		SELECT id, slug, long_url, secret_key_hash, expires_at, max_clicks, password_hash, email, COALESCE(owner_id, 0), COALESCE(workspace_id, 0), threat, disabled_at, created_at, updated_at, COUNT(*) OVER()
 		FROM short_urls
-		WHERE `+strings.Join(where, " AND "), args...)
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+orderBy+`
+		`+limitOffset, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -182,17 +734,103 @@ func findShortUrls(ctx context.Context, tx *Tx, filter suss.ShortURLFilter) ([]*
 			&shortUrl.ID,
 			&shortUrl.Slug,
 			&shortUrl.LongURL,
-			&shortUrl.SecretKey,
+			&shortUrl.SecretKeyHash,
+			(*NullTime)(&shortUrl.ExpiresAt),
+			&shortUrl.MaxClicks,
+			&shortUrl.PasswordHash,
+			&shortUrl.Email,
+			&shortUrl.OwnerID,
+			&shortUrl.WorkspaceID,
+			&shortUrl.Threat,
+			(*NullTime)(&shortUrl.DisabledAt),
 			(*NullTime)(&shortUrl.CreatedAt),
 			(*NullTime)(&shortUrl.UpdatedAt),
 			&n,
 		); err != nil {
 			return nil, 0, err
 		}
+
+		if shortUrl.LongURL, err = tx.db.decrypt(shortUrl.LongURL); err != nil {
+			return nil, 0, err
+		}
+		if shortUrl.Email, err = tx.db.decrypt(shortUrl.Email); err != nil {
+			return nil, 0, err
+		}
 		shortUrls = append(shortUrls, &shortUrl)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if v := filter.LongURL; v != nil {
+		shortUrls, n = filterShortUrlsByLongURL(shortUrls, *v, filter.Offset, filter.Limit)
+	}
+
+	return shortUrls, n, nil
+}
+
+// filterShortUrlsByLongURL returns the page of short urls whose destination
+// contains substr, case-insensitively, and the total number of matches.
+func filterShortUrlsByLongURL(shortUrls []*suss.ShortURL, substr string, offset, limit int) ([]*suss.ShortURL, int) {
+	substr = strings.ToLower(substr)
+
+	matches := make([]*suss.ShortURL, 0)
+	for _, shortUrl := range shortUrls {
+		if strings.Contains(strings.ToLower(shortUrl.LongURL), substr) {
+			matches = append(matches, shortUrl)
+		}
+	}
+
+	n := len(matches)
+	if offset >= n {
+		return []*suss.ShortURL{}, n
+	}
+	matches = matches[offset:]
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+	return matches, n
+}
+
+// shortUrlSortOrders maps sort orders accepted by suss.ShortURLFilter to SQL.
+var shortUrlSortOrders = map[string]string{
+	"":                             "created_at DESC, id DESC",
+	suss.ShortURLSortCreatedAt:     "created_at ASC, id ASC",
+	suss.ShortURLSortCreatedAtDesc: "created_at DESC, id DESC",
+	suss.ShortURLSortUpdatedAt:     "updated_at ASC, id ASC",
+	suss.ShortURLSortUpdatedAtDesc: "updated_at DESC, id DESC",
+}
+
+func findShortUrlByID(ctx context.Context, tx *Tx, id int) (*suss.ShortURL, error) {
+	shortUrls, _, err := findShortUrls(ctx, tx, suss.ShortURLFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	if len(shortUrls) == 0 {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Short Url not found."}
+	}
 
-	return shortUrls, 0, nil
+	return shortUrls[0], nil
+}
+
+func findShortUrlBySecretKey(ctx context.Context, tx *Tx, slug, secretKey string) (*suss.ShortURL, error) {
+	if slug == "" {
+		return nil, suss.Errorf(suss.EINVALID, "slug required")
+	} else if secretKey == "" {
+		return nil, suss.Errorf(suss.EINVALID, "secret required")
+	}
+
+	shortUrl, err := findShortUrlBySlug(ctx, tx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	// compare hashes in constant time to avoid leaking the secret through timing
+	if subtle.ConstantTimeCompare([]byte(tx.db.hash(secretKey)), []byte(shortUrl.SecretKeyHash)) != 1 {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "invalid secret")
+	}
+
+	return shortUrl, nil
 }
 
 func findShortUrlBySlug(ctx context.Context, tx *Tx, slug string) (*suss.ShortURL, error) {
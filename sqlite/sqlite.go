@@ -2,8 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
 	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -11,7 +15,8 @@ import (
 	"sort"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/heyjorgedev/suss"
+	"github.com/mattn/go-sqlite3"
 )
 
 //go:embed migration/*.sql
@@ -22,7 +27,23 @@ type DB struct {
 	ctx    context.Context
 	cancel func()
 
+	// keys used to encrypt & decrypt values, by key id
+	encryptionKey  *encryptionKey
+	encryptionKeys map[string]*encryptionKey
+
 	DSN string
+
+	// key used to hash secrets stored in the database
+	HashKey []byte
+
+	// key used to encrypt values at rest, such as long urls. previous keys
+	// are only used to decrypt values until they are re-encrypted.
+	EncryptionKey          []byte
+	PreviousEncryptionKeys [][]byte
+
+	// optional checker screening the destinations of new short urls, see
+	// CheckShortURLs to screen existing ones
+	URLChecker suss.URLChecker
 }
 
 func NewDB(dsn string) *DB {
@@ -37,6 +58,13 @@ func (db *DB) Open() (err error) {
 	// validate the a dsn is provided
 	if db.DSN == "" {
 		return fmt.Errorf("dsn required")
+	} else if len(db.HashKey) == 0 {
+		return fmt.Errorf("hash key required")
+	}
+
+	// prepare the encryption keys
+	if err := db.openEncryptionKeys(); err != nil {
+		return err
 	}
 
 	// make the parent directory if using a file
@@ -47,7 +75,7 @@ func (db *DB) Open() (err error) {
 	}
 
 	// connect to the database
-	if db.db, err = sql.Open("sqlite3", db.DSN); err != nil {
+	if db.db, err = sql.Open(driverName, db.DSN); err != nil {
 		return err
 	}
 
@@ -61,33 +89,59 @@ func (db *DB) Open() (err error) {
 		return err
 	}
 
+	// hash secret keys stored before they were hashed at rest
+	if err = db.migrateSecretKeys(); err != nil {
+		return fmt.Errorf("cannot migrate secret keys: %w", err)
+	}
+
+	// encrypt long urls stored before they were encrypted at rest
+	if _, err = db.encryptColumn(db.ctx, "short_urls", "long_url", `long_url NOT LIKE ?`, encryptedPrefix+"%"); err != nil {
+		return fmt.Errorf("cannot encrypt long urls: %w", err)
+	}
+
 	return nil
 }
 
 func (db *DB) configure() error {
-	// enable wal
+	// enable wal, which is persistent so only set once
 	if _, err := db.db.Exec(`PRAGMA journal_mode = wal;`); err != nil {
 		return fmt.Errorf("enable wal: %w", err)
 	}
 
+	return nil
+}
+
+// driverName is the sqlite3 driver configuring every connection, see configureConn.
+const driverName = "sqlite3_suss"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: configureConn,
+	})
+}
+
+// configureConn sets the pragmas which only apply to the connection they are
+// run on. It is called for every connection the pool opens, otherwise only the
+// connection used by configure would enforce foreign keys.
+func configureConn(conn *sqlite3.SQLiteConn) error {
 	// enable foreign keys
-	if _, err := db.db.Exec(`PRAGMA foreign_keys = ON;`); err != nil {
+	if _, err := conn.Exec(`PRAGMA foreign_keys = ON;`, nil); err != nil {
 		return fmt.Errorf("enable foreign keys: %w", err)
 	}
 
 	// configure busy timeout
-	if _, err := db.db.Exec(`PRAGMA busy_timeout = 5000;`); err != nil {
+	if _, err := conn.Exec(`PRAGMA busy_timeout = 5000;`, nil); err != nil {
 		return fmt.Errorf("set busy_timeout: %w", err)
 	}
 
 	// configure synchronous mode
 	// note: trades a little durability for much faster writes
-	if _, err := db.db.Exec(`PRAGMA synchronous = NORMAL;`); err != nil {
+	if _, err := conn.Exec(`PRAGMA synchronous = NORMAL;`, nil); err != nil {
 		return fmt.Errorf("set synchronous: %w", err)
 	}
 
 	// configure temp store in memory (instead of disk)
-	if _, err := db.db.Exec(`PRAGMA temp_store = MEMORY;`); err != nil {
+	if _, err := conn.Exec(`PRAGMA temp_store = MEMORY;`, nil); err != nil {
 		return fmt.Errorf("set temp_store: %w", err)
 	}
 
@@ -145,6 +199,55 @@ func (db *DB) migrateFile(name string) error {
 	return tx.Commit()
 }
 
+// migrateSecretKeys replaces any plaintext secret keys with their hash. It is
+// a no-op once every row has been migrated.
+func (db *DB) migrateSecretKeys() error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, secret_key FROM short_urls WHERE secret_key <> ''`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	secretKeys := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var secretKey string
+		if err := rows.Scan(&id, &secretKey); err != nil {
+			return err
+		}
+		secretKeys[id] = secretKey
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, secretKey := range secretKeys {
+		if _, err := tx.Exec(`
+			UPDATE short_urls
+			SET secret_key = '',
+			    secret_key_hash = ?
+			WHERE id = ?
+		`, db.hash(secretKey), id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// hash returns a hex encoded HMAC-SHA256 of v keyed by the database's HashKey.
+func (db *DB) hash(v string) string {
+	h := hmac.New(sha256.New, db.HashKey)
+	h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func (db *DB) Close() error {
 	// cancel background context.
 	db.cancel()
@@ -169,3 +272,22 @@ func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 		now: time.Now().UTC().Truncate(time.Second),
 	}, nil
 }
+
+// FormatLimitOffset returns a SQL string for a given limit & offset.
+// Clauses are only added if limit and/or offset are greater than zero.
+func FormatLimitOffset(limit, offset int) string {
+	if limit > 0 && offset > 0 {
+		return fmt.Sprintf(`LIMIT %d OFFSET %d`, limit, offset)
+	} else if limit > 0 {
+		return fmt.Sprintf(`LIMIT %d`, limit)
+	} else if offset > 0 {
+		return fmt.Sprintf(`LIMIT -1 OFFSET %d`, offset)
+	}
+	return ""
+}
+
+// isUniqueConstraintError returns true if err was caused by a UNIQUE constraint violation.
+func isUniqueConstraintError(err error) bool {
+	var e sqlite3.Error
+	return errors.As(err, &e) && e.ExtendedCode == sqlite3.ErrConstraintUnique
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// MustOpenDB returns a new DB backed by a temporary file.
+func MustOpenDB(tb testing.TB) *DB {
+	tb.Helper()
+
+	db := NewDB(filepath.Join(tb.TempDir(), "db"))
+	db.HashKey = []byte("hash key")
+	db.EncryptionKey = []byte("0123456789abcdef0123456789abcdef")
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDB_ForeignKeys(t *testing.T) {
+	db := MustOpenDB(t)
+
+	// hold several connections at once so the pool has to open new ones
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		conn, err := db.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		var enabled bool
+		if err := conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&enabled); err != nil {
+			t.Fatal(err)
+		} else if !enabled {
+			t.Fatalf("foreign keys disabled on connection %d", i)
+		}
+	}
+}
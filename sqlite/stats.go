@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type StatsService struct {
+	db *DB
+}
+
+func NewStatsService(db *DB) *StatsService {
+	return &StatsService{
+		db: db,
+	}
+}
+
+func (s *StatsService) FindInstanceStats(ctx context.Context, days int) (*suss.InstanceStats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	} else if days < 1 {
+		return nil, suss.Errorf(suss.EINVALID, "Days must be at least one.")
+	}
+
+	var stats suss.InstanceStats
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM short_urls),
+			(SELECT COUNT(*) FROM clicks),
+			(SELECT COUNT(*) FROM users)
+	`).Scan(
+		&stats.ShortURLs,
+		&stats.Clicks,
+		&stats.Users,
+	); err != nil {
+		return nil, err
+	}
+
+	// times are stored as UTC RFC 3339 strings, so the day is their prefix
+	since := tx.now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	if stats.ShortURLsPerDay, err = findDailyCounts(ctx, tx, "short_urls", since, days); err != nil {
+		return nil, err
+	}
+	if stats.ClicksPerDay, err = findDailyCounts(ctx, tx, "clicks", since, days); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// findDailyCounts returns the number of rows of table created on each of the
+// days starting at since, including days without any.
+func findDailyCounts(ctx context.Context, tx *Tx, table string, since time.Time, days int) ([]suss.DailyCount, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT substr(created_at, 1, 10), COUNT(*)
+		FROM `+table+`
+		WHERE created_at >= ?
+		GROUP BY 1
+	`, (*NullTime)(&since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var n int
+		if err := rows.Scan(&day, &n); err != nil {
+			return nil, err
+		}
+		counts[day] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	daily := make([]suss.DailyCount, days)
+	for i := range daily {
+		date := since.AddDate(0, 0, i)
+		daily[i] = suss.DailyCount{Date: date, Count: counts[date.Format(time.DateOnly)]}
+	}
+	return daily, nil
+}
@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestStatsService_FindInstanceStats(t *testing.T) {
+	ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/0"})
+		MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/1"})
+		old := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/2"})
+		if err := NewClickService(db).Create(context.Background(), &suss.Click{ShortURLID: shortUrl.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		// created two days ago, the first of the three days
+		createdAt := time.Now().UTC().AddDate(0, 0, -2)
+		if _, err := db.db.Exec(`UPDATE short_urls SET created_at = ? WHERE id = ?`, (*NullTime)(&createdAt), old.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := NewStatsService(db).FindInstanceStats(ctx, 3)
+		if err != nil {
+			t.Fatal(err)
+		} else if stats.ShortURLs != 3 || stats.Clicks != 1 || stats.Users != 0 {
+			t.Fatalf("ShortURLs=%d, Clicks=%d, Users=%d", stats.ShortURLs, stats.Clicks, stats.Users)
+		} else if len(stats.ShortURLsPerDay) != 3 || len(stats.ClicksPerDay) != 3 {
+			t.Fatalf("len=%d, %d", len(stats.ShortURLsPerDay), len(stats.ClicksPerDay))
+		}
+		for i, want := range []int{1, 0, 2} {
+			if got := stats.ShortURLsPerDay[i].Count; got != want {
+				t.Fatalf("ShortURLsPerDay[%d]=%d, want %d", i, got, want)
+			}
+		}
+		if got := stats.ClicksPerDay[2].Count; got != 1 {
+			t.Fatalf("ClicksPerDay[2]=%d", got)
+		} else if got, want := stats.ShortURLsPerDay[2].Date.Format(time.DateOnly), time.Now().UTC().Format(time.DateOnly); got != want {
+			t.Fatalf("Date=%s, want %s", got, want)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		db := MustOpenDB(t)
+		_, userCtx := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if _, err := NewStatsService(db).FindInstanceStats(userCtx, 3); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrDays", func(t *testing.T) {
+		if _, err := NewStatsService(MustOpenDB(t)).FindInstanceStats(ctx, 0); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// checkURLThreat returns an error if the url checker flags longURL.
+func checkURLThreat(ctx context.Context, tx *Tx, longURL string) error {
+	if tx.db.URLChecker == nil {
+		return nil
+	}
+
+	threat, err := tx.db.URLChecker.CheckURL(ctx, longURL)
+	if err != nil {
+		return fmt.Errorf("cannot check url: %w", err)
+	} else if threat != "" {
+		return suss.Errorf(suss.EINVALID, "This url is flagged as unsafe (%s) and cannot be shortened.", threat)
+	}
+	return nil
+}
+
+// CheckShortURLs screens the destination of every short url with the url
+// checker again, flagging newly listed ones and clearing ones no longer
+// listed. Returns the number of short urls currently flagged.
+func (db *DB) CheckShortURLs(ctx context.Context) (flagged int, err error) {
+	const batchSize = 500
+
+	if db.URLChecker == nil {
+		return 0, nil
+	}
+
+	for afterID := 0; ; {
+		n, lastID, err := db.checkShortURLsBatch(ctx, afterID, batchSize)
+		if flagged += n; err != nil {
+			return flagged, err
+		} else if lastID == afterID {
+			return flagged, nil
+		}
+		afterID = lastID
+	}
+}
+
+// checkShortURLsBatch checks up to limit short urls after afterID and returns
+// the number flagged along with the last id checked. Urls are checked outside
+// of the transactions so a slow checker does not hold the database. Changes are
+// audited with the system as their actor.
+func (db *DB) checkShortURLsBatch(ctx context.Context, afterID, limit int) (flagged, lastID int, err error) {
+	type shortUrlThreat struct {
+		id      int
+		longURL string
+		threat  string
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, afterID, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, long_url, threat
+		FROM short_urls
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return 0, afterID, err
+	}
+	defer rows.Close()
+
+	var batch []shortUrlThreat
+	for rows.Next() {
+		var v shortUrlThreat
+		if err := rows.Scan(&v.id, &v.longURL, &v.threat); err != nil {
+			return 0, afterID, err
+		}
+		if v.longURL, err = db.decrypt(v.longURL); err != nil {
+			return 0, afterID, fmt.Errorf("short_urls %d: %w", v.id, err)
+		}
+		batch = append(batch, v)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, afterID, err
+	}
+	tx.Rollback()
+
+	if len(batch) == 0 {
+		return 0, afterID, nil
+	}
+	lastID = batch[len(batch)-1].id
+
+	var changed []shortUrlThreat
+	for _, v := range batch {
+		threat, err := db.URLChecker.CheckURL(ctx, v.longURL)
+		if err != nil {
+			return 0, afterID, fmt.Errorf("cannot check url: %w", err)
+		}
+		if threat != "" {
+			flagged++
+		}
+		if threat != v.threat {
+			v.threat = threat
+			changed = append(changed, v)
+		}
+	}
+
+	if len(changed) == 0 {
+		return flagged, lastID, nil
+	}
+
+	if tx, err = db.BeginTx(ctx, nil); err != nil {
+		return 0, afterID, err
+	}
+	defer tx.Rollback()
+
+	for _, v := range changed {
+		// skip short urls deleted since they were read
+		before, err := findShortUrlByID(ctx, tx, v.id)
+		if suss.ErrorCode(err) == suss.ENOTFOUND {
+			continue
+		} else if err != nil {
+			return 0, afterID, err
+		}
+
+		after := *before
+		after.Threat = v.threat
+		if _, err := tx.ExecContext(ctx, `UPDATE short_urls SET threat = ? WHERE id = ?`, after.Threat, after.ID); err != nil {
+			return 0, afterID, err
+		}
+
+		action := suss.AuditShortURLFlag
+		if after.Threat == "" {
+			action = suss.AuditShortURLUnflag
+		}
+		if err := auditEventCreate(ctx, tx, action, before, &after); err != nil {
+			return 0, afterID, err
+		}
+	}
+
+	return flagged, lastID, tx.Commit()
+}
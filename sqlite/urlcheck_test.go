@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// URLChecker flags urls containing "evil" as malware.
+type URLChecker struct{}
+
+func (URLChecker) CheckURL(ctx context.Context, url string) (string, error) {
+	if strings.Contains(url, "evil") {
+		return "MALWARE", nil
+	}
+	return "", nil
+}
+
+func TestDB_CheckShortURLs(t *testing.T) {
+	db := MustOpenDB(t)
+	shortUrl := MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://evil.example/"})
+	MustCreateShortURL(t, context.Background(), db, &suss.ShortURL{LongURL: "https://example.com/"})
+
+	// the url was listed after the link was created
+	db.URLChecker = URLChecker{}
+	if flagged, err := db.CheckShortURLs(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if flagged != 1 {
+		t.Fatalf("flagged=%d", flagged)
+	}
+
+	ctx := suss.NewContextWithUser(context.Background(), &suss.User{IsAdmin: true})
+	action := suss.AuditShortURLFlag
+	events, _, err := NewAuditService(db).FindAuditEvents(ctx, suss.AuditEventFilter{Action: &action})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(events) != 1 {
+		t.Fatalf("len(events)=%d", len(events))
+	} else if got, want := events[0].ShortURLID, shortUrl.ID; got != want {
+		t.Fatalf("ShortURLID=%d, want %d", got, want)
+	} else if got, want := events[0].ActorType, suss.AuditActorSystem; got != want {
+		t.Fatalf("ActorType=%q, want %q", got, want)
+	}
+
+	// checking again changes nothing
+	if _, err := db.CheckShortURLs(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, n, err := NewAuditService(db).FindAuditEvents(ctx, suss.AuditEventFilter{Action: &action}); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("n=%d", n)
+	}
+}
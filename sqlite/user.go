@@ -0,0 +1,303 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// dummyPasswordHash is compared against when no user matches an email so
+// failed sign ins take as long whether or not the account exists.
+var dummyPasswordHash, _ = suss.HashPassword("dummy password")
+
+type UserService struct {
+	db *DB
+}
+
+func NewUserService(db *DB) *UserService {
+	return &UserService{
+		db: db,
+	}
+}
+
+func (s *UserService) FindUserByID(ctx context.Context, id int) (*suss.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findUserByID(ctx, tx, id)
+}
+
+func (s *UserService) FindUsers(ctx context.Context, filter suss.UserFilter) ([]*suss.User, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findUsers(ctx, tx, filter)
+}
+
+func (s *UserService) Authenticate(ctx context.Context, email, password string) (*suss.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	users, _, err := findUsers(ctx, tx, suss.UserFilter{Email: &email})
+	if err != nil {
+		return nil, err
+	} else if len(users) == 0 {
+		suss.ComparePassword(dummyPasswordHash, password)
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Invalid email or password.")
+	}
+
+	if !suss.ComparePassword(users[0].PasswordHash, password) {
+		return nil, suss.Errorf(suss.EUNAUTHORIZED, "Invalid email or password.")
+	}
+	return users[0], nil
+}
+
+func (s *UserService) Create(ctx context.Context, user *suss.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := userCreate(ctx, tx, user); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *UserService) Update(ctx context.Context, id int, upd suss.UserUpdate) (*suss.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := userUpdate(ctx, tx, id, upd)
+	if err != nil {
+		return user, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func (s *UserService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := userDelete(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func userCreate(ctx context.Context, tx *Tx, u *suss.User) error {
+	// hash the password, the plaintext is never stored
+	if u.Password != "" {
+		if err := suss.ValidatePassword(u.Password); err != nil {
+			return err
+		}
+		hash, err := suss.HashPassword(u.Password)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash, u.Password = hash, ""
+	}
+
+	// set created and updated at
+	u.CreatedAt = tx.now
+	u.UpdatedAt = u.CreatedAt
+
+	// validate the user
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	email, err := tx.db.encrypt(u.Email)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO users (name, email, email_hash, password_hash, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, u.Name, email, tx.db.hash(u.Email), u.PasswordHash, (*NullTime)(&u.CreatedAt), (*NullTime)(&u.UpdatedAt))
+	if isUniqueConstraintError(err) {
+		return suss.Errorf(suss.ECONFLICT, "An account with this email already exists.")
+	} else if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	u.ID = int(id)
+
+	return nil
+}
+
+func userUpdate(ctx context.Context, tx *Tx, id int, upd suss.UserUpdate) (*suss.User, error) {
+	// fetch the current user
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return user, err
+	}
+
+	// update fields
+	if v := upd.Name; v != nil {
+		user.Name = *v
+	}
+	if v := upd.Email; v != nil {
+		user.Email = *v
+	}
+	if v := upd.Password; v != nil {
+		if err := suss.ValidatePassword(*v); err != nil {
+			return user, err
+		}
+		if user.PasswordHash, err = suss.HashPassword(*v); err != nil {
+			return user, err
+		}
+	}
+	if v := upd.IsAdmin; v != nil {
+		if current := suss.UserFromContext(ctx); current != nil && !current.IsAdmin {
+			return user, suss.Errorf(suss.EUNAUTHORIZED, "Only administrators can change the admin role.")
+		}
+		user.IsAdmin = *v
+	}
+
+	// set last updated at
+	user.UpdatedAt = tx.now
+
+	// validate the user
+	if err := user.Validate(); err != nil {
+		return user, err
+	}
+
+	email, err := tx.db.encrypt(user.Email)
+	if err != nil {
+		return user, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET name = ?,
+		    email = ?,
+		    email_hash = ?,
+		    password_hash = ?,
+		    is_admin = ?,
+		    updated_at = ?
+		WHERE id = ?
+	`, user.Name, email, tx.db.hash(user.Email), user.PasswordHash, user.IsAdmin, (*NullTime)(&user.UpdatedAt), id); isUniqueConstraintError(err) {
+		return user, suss.Errorf(suss.ECONFLICT, "An account with this email already exists.")
+	} else if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+func userDelete(ctx context.Context, tx *Tx, id int) error {
+	// verify the user exists
+	if _, err := findUserByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func findUsers(ctx context.Context, tx *Tx, filter suss.UserFilter) ([]*suss.User, int, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.Email; v != nil {
+		// emails are encrypted, look them up by the hash of the normalized email
+		email, err := suss.NormalizeEmail(*v)
+		if err != nil {
+			return []*suss.User{}, 0, nil
+		}
+		where, args = append(where, "email_hash = ?"), append(args, tx.db.hash(email))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, name, email, password_hash, is_admin, created_at, updated_at, COUNT(*) OVER()
+		FROM users
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	users := make([]*suss.User, 0)
+	for rows.Next() {
+		var user suss.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.Name,
+			&user.Email,
+			&user.PasswordHash,
+			&user.IsAdmin,
+			(*NullTime)(&user.CreatedAt),
+			(*NullTime)(&user.UpdatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		if user.Email, err = tx.db.decrypt(user.Email); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, n, nil
+}
+
+// requireAdmin returns an error unless the current user is an administrator.
+func requireAdmin(ctx context.Context) error {
+	if user := suss.UserFromContext(ctx); user == nil || !user.IsAdmin {
+		return suss.Errorf(suss.EUNAUTHORIZED, "You must be an administrator to do this.")
+	}
+	return nil
+}
+
+func findUserByID(ctx context.Context, tx *Tx, id int) (*suss.User, error) {
+	users, _, err := findUsers(ctx, tx, suss.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "User not found."}
+	}
+
+	return users[0], nil
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/heyjorgedev/suss"
+)
+
+func TestUserService_Authenticate(t *testing.T) {
+	db := MustOpenDB(t)
+	s := NewUserService(db)
+	user, _ := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+	t.Run("OK", func(t *testing.T) {
+		// emails are matched case-insensitively
+		if other, err := s.Authenticate(context.Background(), "Susy@Example.com", "password123"); err != nil {
+			t.Fatal(err)
+		} else if other.ID != user.ID {
+			t.Fatalf("ID=%d, want %d", other.ID, user.ID)
+		}
+	})
+
+	t.Run("ErrInvalidPassword", func(t *testing.T) {
+		if _, err := s.Authenticate(context.Background(), "susy@example.com", "password124"); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrUnknownEmail", func(t *testing.T) {
+		if _, err := s.Authenticate(context.Background(), "john@example.com", "password123"); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestUserService_Create(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		user, _ := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if user.Password != "" {
+			t.Fatal("expected password to be cleared")
+		} else if user.PasswordHash == "" || user.PasswordHash == "password123" {
+			t.Fatalf("PasswordHash=%q", user.PasswordHash)
+		}
+	})
+
+	t.Run("ErrEmailTaken", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+		if err := NewUserService(db).Create(context.Background(), &suss.User{Name: "Susy", Email: "SUSY@example.com", Password: "password123"}); suss.ErrorCode(err) != suss.ECONFLICT {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrPasswordTooShort", func(t *testing.T) {
+		db := MustOpenDB(t)
+		if err := NewUserService(db).Create(context.Background(), &suss.User{Name: "Susy", Email: "susy@example.com", Password: "short"}); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSessionService(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewSessionService(db)
+		user, _ := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+		session := &suss.Session{UserID: user.ID}
+		if err := s.Create(context.Background(), session); err != nil {
+			t.Fatal(err)
+		} else if session.Token == "" {
+			t.Fatal("expected token")
+		}
+
+		if other, err := s.FindSessionByToken(context.Background(), session.Token); err != nil {
+			t.Fatal(err)
+		} else if other.ID != session.ID || other.User == nil || other.User.ID != user.ID {
+			t.Fatalf("unexpected session: %#v", other)
+		}
+
+		if err := s.Delete(context.Background(), session.ID); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.FindSessionByToken(context.Background(), session.Token); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrExpired", func(t *testing.T) {
+		db := MustOpenDB(t)
+		s := NewSessionService(db)
+		user, _ := MustCreateUser(t, db, &suss.User{Name: "Susy", Email: "susy@example.com", Password: "password123"})
+
+		session := &suss.Session{UserID: user.ID}
+		if err := s.Create(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+		expiresAt := time.Now().Add(-time.Minute)
+		if _, err := db.db.Exec(`UPDATE sessions SET expires_at = ? WHERE id = ?`, (*NullTime)(&expiresAt), session.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.FindSessionByToken(context.Background(), session.Token); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("ErrUserNotFound", func(t *testing.T) {
+		db := MustOpenDB(t)
+		if err := NewSessionService(db).Create(context.Background(), &suss.Session{UserID: 1000}); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,448 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/heyjorgedev/suss"
+)
+
+type WorkspaceService struct {
+	db *DB
+}
+
+func NewWorkspaceService(db *DB) *WorkspaceService {
+	return &WorkspaceService{
+		db: db,
+	}
+}
+
+func (s *WorkspaceService) FindWorkspaceByID(ctx context.Context, id int) (*suss.Workspace, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findWorkspaceByID(ctx, tx, id)
+}
+
+func (s *WorkspaceService) FindWorkspaces(ctx context.Context, filter suss.WorkspaceFilter) ([]*suss.Workspace, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findWorkspaces(ctx, tx, filter)
+}
+
+func (s *WorkspaceService) Create(ctx context.Context, workspace *suss.Workspace) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := workspaceCreate(ctx, tx, workspace); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *WorkspaceService) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := requireWorkspaceRole(ctx, tx, id, suss.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	// links outlive their workspace, detach them rather than relying on the
+	// ON DELETE SET NULL foreign key alone, so each change is audited
+	shortUrls, _, err := findShortUrls(ctx, tx, suss.ShortURLFilter{WorkspaceID: &id})
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE short_urls SET workspace_id = NULL WHERE workspace_id = ?`, id); err != nil {
+		return err
+	}
+	for _, before := range shortUrls {
+		after := *before
+		after.WorkspaceID = 0
+		if err := auditEventCreate(ctx, tx, suss.AuditShortURLUpdate, before, &after); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspaces WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *WorkspaceService) FindWorkspaceMembers(ctx context.Context, workspaceID int) ([]*suss.WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := requireWorkspaceRole(ctx, tx, workspaceID, suss.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return findWorkspaceMembers(ctx, tx, "workspace_id = ?", workspaceID)
+}
+
+func (s *WorkspaceService) InviteWorkspaceMember(ctx context.Context, workspaceID int, email, role string) (*suss.WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	member, err := workspaceMemberInvite(ctx, tx, workspaceID, email, role)
+	if err != nil {
+		return member, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return member, err
+	}
+
+	return member, nil
+}
+
+func (s *WorkspaceService) UpdateWorkspaceMember(ctx context.Context, id int, role string) (*suss.WorkspaceMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	member, err := workspaceMemberUpdate(ctx, tx, id, role)
+	if err != nil {
+		return member, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return member, err
+	}
+
+	return member, nil
+}
+
+func (s *WorkspaceService) RemoveWorkspaceMember(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := workspaceMemberRemove(ctx, tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func workspaceCreate(ctx context.Context, tx *Tx, w *suss.Workspace) error {
+	userID := suss.UserIDFromContext(ctx)
+	if userID == 0 {
+		return suss.Errorf(suss.EUNAUTHORIZED, "You must be signed in to create a workspace.")
+	}
+
+	// set created and updated at
+	w.CreatedAt = tx.now
+	w.UpdatedAt = w.CreatedAt
+
+	// validate the workspace
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO workspaces (name, created_at, updated_at)
+		VALUES (?, ?, ?)
+	`, w.Name, (*NullTime)(&w.CreatedAt), (*NullTime)(&w.UpdatedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	w.ID = int(id)
+
+	// the creator is the first owner of the workspace
+	w.Role = suss.WorkspaceRoleOwner
+	return workspaceMemberCreate(ctx, tx, &suss.WorkspaceMember{
+		WorkspaceID: w.ID,
+		UserID:      userID,
+		Role:        w.Role,
+	})
+}
+
+func workspaceMemberCreate(ctx context.Context, tx *Tx, m *suss.WorkspaceMember) error {
+	// set created at
+	m.CreatedAt = tx.now
+
+	// validate the member
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO workspace_members (workspace_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`, m.WorkspaceID, m.UserID, m.Role, (*NullTime)(&m.CreatedAt))
+	if isUniqueConstraintError(err) {
+		return suss.Errorf(suss.ECONFLICT, "User is already a member of this workspace.")
+	} else if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	m.ID = int(id)
+
+	return nil
+}
+
+func workspaceMemberInvite(ctx context.Context, tx *Tx, workspaceID int, email, role string) (*suss.WorkspaceMember, error) {
+	if _, err := requireWorkspaceRole(ctx, tx, workspaceID, suss.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	users, _, err := findUsers(ctx, tx, suss.UserFilter{Email: &email})
+	if err != nil {
+		return nil, err
+	} else if len(users) == 0 {
+		return nil, suss.Errorf(suss.ENOTFOUND, "No account uses this email, ask them to register first.")
+	}
+
+	member := &suss.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      users[0].ID,
+		User:        users[0],
+		Role:        role,
+	}
+	if err := workspaceMemberCreate(ctx, tx, member); err != nil {
+		return member, err
+	}
+	return member, nil
+}
+
+func workspaceMemberUpdate(ctx context.Context, tx *Tx, id int, role string) (*suss.WorkspaceMember, error) {
+	member, err := findWorkspaceMemberByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := requireWorkspaceRole(ctx, tx, member.WorkspaceID, suss.WorkspaceRoleOwner); err != nil {
+		return member, err
+	}
+
+	// keep at least one owner to manage the workspace
+	if member.Role == suss.WorkspaceRoleOwner && role != suss.WorkspaceRoleOwner {
+		if err := requireAnotherWorkspaceOwner(ctx, tx, member); err != nil {
+			return member, err
+		}
+	}
+
+	member.Role = role
+	if err := member.Validate(); err != nil {
+		return member, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE workspace_members SET role = ? WHERE id = ?`, member.Role, id); err != nil {
+		return member, err
+	}
+
+	return member, nil
+}
+
+func workspaceMemberRemove(ctx context.Context, tx *Tx, id int) error {
+	member, err := findWorkspaceMemberByID(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	// members can leave a workspace, only owners can remove others
+	if member.UserID != suss.UserIDFromContext(ctx) {
+		if _, err := requireWorkspaceRole(ctx, tx, member.WorkspaceID, suss.WorkspaceRoleOwner); err != nil {
+			return err
+		}
+	}
+
+	if member.Role == suss.WorkspaceRoleOwner {
+		if err := requireAnotherWorkspaceOwner(ctx, tx, member); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM workspace_members WHERE id = ?`, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// requireAnotherWorkspaceOwner returns an EINVALID error if member is the last
+// owner of their workspace.
+func requireAnotherWorkspaceOwner(ctx context.Context, tx *Tx, member *suss.WorkspaceMember) error {
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM workspace_members
+		WHERE workspace_id = ? AND role = ? AND id <> ?
+	`, member.WorkspaceID, suss.WorkspaceRoleOwner, member.ID).Scan(&n); err != nil {
+		return err
+	} else if n == 0 {
+		return suss.Errorf(suss.EINVALID, "A workspace must keep at least one owner.")
+	}
+	return nil
+}
+
+// requireWorkspaceRole returns the role of the current user in a workspace,
+// or an EUNAUTHORIZED error if they are not a member with at least role min.
+func requireWorkspaceRole(ctx context.Context, tx *Tx, workspaceID int, min string) (string, error) {
+	userID := suss.UserIDFromContext(ctx)
+	if userID == 0 {
+		return "", suss.Errorf(suss.EUNAUTHORIZED, "You must be signed in to access this workspace.")
+	}
+
+	var role string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT role
+		FROM workspace_members
+		WHERE workspace_id = ? AND user_id = ?
+	`, workspaceID, userID).Scan(&role); err == sql.ErrNoRows {
+		return "", suss.Errorf(suss.EUNAUTHORIZED, "You are not a member of this workspace.")
+	} else if err != nil {
+		return "", err
+	}
+
+	if !suss.HasWorkspaceRole(role, min) {
+		return role, suss.Errorf(suss.EUNAUTHORIZED, "You must be a workspace %s to do this.", min)
+	}
+	return role, nil
+}
+
+func findWorkspaces(ctx context.Context, tx *Tx, filter suss.WorkspaceFilter) ([]*suss.Workspace, int, error) {
+	// only return workspaces the current user is a member of
+	where, args := []string{"m.user_id = ?"}, []interface{}{suss.UserIDFromContext(ctx)}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "w.id = ?"), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT w.id, w.name, m.role, w.created_at, w.updated_at, COUNT(*) OVER()
+		FROM workspaces w
+		INNER JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY w.id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	workspaces := make([]*suss.Workspace, 0)
+	for rows.Next() {
+		var workspace suss.Workspace
+		if err := rows.Scan(
+			&workspace.ID,
+			&workspace.Name,
+			&workspace.Role,
+			(*NullTime)(&workspace.CreatedAt),
+			(*NullTime)(&workspace.UpdatedAt),
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		workspaces = append(workspaces, &workspace)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return workspaces, n, nil
+}
+
+func findWorkspaceByID(ctx context.Context, tx *Tx, id int) (*suss.Workspace, error) {
+	workspaces, _, err := findWorkspaces(ctx, tx, suss.WorkspaceFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	if len(workspaces) == 0 {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Workspace not found."}
+	}
+
+	return workspaces[0], nil
+}
+
+// findWorkspaceMembers returns the members matching the where clause, along
+// with their user.
+func findWorkspaceMembers(ctx context.Context, tx *Tx, where string, args ...interface{}) ([]*suss.WorkspaceMember, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, workspace_id, user_id, role, created_at
+		FROM workspace_members
+		WHERE `+where+`
+		ORDER BY id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*suss.WorkspaceMember, 0)
+	for rows.Next() {
+		var member suss.WorkspaceMember
+		if err := rows.Scan(
+			&member.ID,
+			&member.WorkspaceID,
+			&member.UserID,
+			&member.Role,
+			(*NullTime)(&member.CreatedAt),
+		); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, member := range members {
+		if member.User, err = findUserByID(ctx, tx, member.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return members, nil
+}
+
+func findWorkspaceMemberByID(ctx context.Context, tx *Tx, id int) (*suss.WorkspaceMember, error) {
+	members, err := findWorkspaceMembers(ctx, tx, "id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, &suss.Error{Code: suss.ENOTFOUND, Message: "Workspace member not found."}
+	}
+
+	return members[0], nil
+}
@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/heyjorgedev/suss"
+)
+
+// workspaceFixture is a workspace with a member of each role and a user who is
+// not a member.
+type workspaceFixture struct {
+	db        *DB
+	workspace *suss.Workspace
+
+	ownerCtx, editorCtx, viewerCtx, outsiderCtx context.Context
+}
+
+// MustCreateWorkspace creates a workspace with a member of each role.
+func MustCreateWorkspace(tb testing.TB, db *DB) *workspaceFixture {
+	tb.Helper()
+	s := NewWorkspaceService(db)
+	f := &workspaceFixture{db: db, workspace: &suss.Workspace{Name: "Team"}}
+
+	_, f.ownerCtx = MustCreateUser(tb, db, &suss.User{Name: "Owner", Email: "owner@example.com", Password: "password123"})
+	_, f.editorCtx = MustCreateUser(tb, db, &suss.User{Name: "Editor", Email: "editor@example.com", Password: "password123"})
+	_, f.viewerCtx = MustCreateUser(tb, db, &suss.User{Name: "Viewer", Email: "viewer@example.com", Password: "password123"})
+	_, f.outsiderCtx = MustCreateUser(tb, db, &suss.User{Name: "Outsider", Email: "outsider@example.com", Password: "password123"})
+
+	if err := s.Create(f.ownerCtx, f.workspace); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := s.InviteWorkspaceMember(f.ownerCtx, f.workspace.ID, "editor@example.com", suss.WorkspaceRoleEditor); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := s.InviteWorkspaceMember(f.ownerCtx, f.workspace.ID, "viewer@example.com", suss.WorkspaceRoleViewer); err != nil {
+		tb.Fatal(err)
+	}
+	return f
+}
+
+func TestWorkspaceService_Roles(t *testing.T) {
+	t.Run("CreateShortURL", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewShortURLService(f.db)
+
+		MustCreateShortURL(t, f.editorCtx, f.db, &suss.ShortURL{LongURL: "https://example.com/", WorkspaceID: f.workspace.ID})
+		for _, ctx := range []context.Context{f.viewerCtx, f.outsiderCtx, context.Background()} {
+			if err := s.Create(ctx, &suss.ShortURL{LongURL: "https://example.com/", WorkspaceID: f.workspace.ID}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	t.Run("UpdateShortURL", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewShortURLService(f.db)
+		shortUrl := MustCreateShortURL(t, f.ownerCtx, f.db, &suss.ShortURL{LongURL: "https://example.com/", WorkspaceID: f.workspace.ID})
+
+		// editors change links created by other members
+		longURL := "https://example.com/new"
+		if _, err := s.Update(f.editorCtx, shortUrl.ID, suss.ShortURLUpdate{LongURL: &longURL}); err != nil {
+			t.Fatal(err)
+		}
+		for _, ctx := range []context.Context{f.viewerCtx, f.outsiderCtx} {
+			if _, err := s.Update(ctx, shortUrl.ID, suss.ShortURLUpdate{LongURL: &longURL}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Delete(ctx, shortUrl.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if err := s.Delete(f.editorCtx, shortUrl.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("FindShortUrls", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewShortURLService(f.db)
+		MustCreateShortURL(t, f.editorCtx, f.db, &suss.ShortURL{LongURL: "https://example.com/", WorkspaceID: f.workspace.ID})
+
+		if _, n, err := s.FindShortUrls(f.viewerCtx, suss.ShortURLFilter{WorkspaceID: &f.workspace.ID}); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("n=%d", n)
+		}
+		if _, _, err := s.FindShortUrls(f.outsiderCtx, suss.ShortURLFilter{WorkspaceID: &f.workspace.ID}); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("FindWorkspaceMembers", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+		if members, err := s.FindWorkspaceMembers(f.viewerCtx, f.workspace.ID); err != nil {
+			t.Fatal(err)
+		} else if len(members) != 3 {
+			t.Fatalf("len=%d", len(members))
+		}
+		if _, err := s.FindWorkspaceMembers(f.outsiderCtx, f.workspace.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+		if err := s.Delete(f.editorCtx, f.workspace.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		} else if err := s.Delete(f.ownerCtx, f.workspace.ID); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestWorkspaceService_Members(t *testing.T) {
+	// findMember returns the membership of the user with email.
+	findMember := func(t *testing.T, f *workspaceFixture, email string) *suss.WorkspaceMember {
+		t.Helper()
+		members, err := NewWorkspaceService(f.db).FindWorkspaceMembers(f.ownerCtx, f.workspace.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range members {
+			if m.User.Email == email {
+				return m
+			}
+		}
+		t.Fatalf("member not found: %s", email)
+		return nil
+	}
+
+	t.Run("ErrInvite", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+
+		// only owners invite members
+		if _, err := s.InviteWorkspaceMember(f.editorCtx, f.workspace.ID, "outsider@example.com", suss.WorkspaceRoleViewer); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.InviteWorkspaceMember(f.ownerCtx, f.workspace.ID, "editor@example.com", suss.WorkspaceRoleViewer); suss.ErrorCode(err) != suss.ECONFLICT {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.InviteWorkspaceMember(f.ownerCtx, f.workspace.ID, "nobody@example.com", suss.WorkspaceRoleViewer); suss.ErrorCode(err) != suss.ENOTFOUND {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.InviteWorkspaceMember(f.ownerCtx, f.workspace.ID, "outsider@example.com", "admin"); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("UpdateRole", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+		viewer := findMember(t, f, "viewer@example.com")
+
+		if _, err := s.UpdateWorkspaceMember(f.editorCtx, viewer.ID, suss.WorkspaceRoleEditor); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := s.UpdateWorkspaceMember(f.ownerCtx, viewer.ID, suss.WorkspaceRoleEditor); err != nil {
+			t.Fatal(err)
+		}
+		MustCreateShortURL(t, f.viewerCtx, f.db, &suss.ShortURL{LongURL: "https://example.com/", WorkspaceID: f.workspace.ID})
+	})
+
+	t.Run("ErrLastOwner", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+		owner := findMember(t, f, "owner@example.com")
+
+		if _, err := s.UpdateWorkspaceMember(f.ownerCtx, owner.ID, suss.WorkspaceRoleEditor); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.RemoveWorkspaceMember(f.ownerCtx, owner.ID); suss.ErrorCode(err) != suss.EINVALID {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		f := MustCreateWorkspace(t, MustOpenDB(t))
+		s := NewWorkspaceService(f.db)
+		editor, viewer := findMember(t, f, "editor@example.com"), findMember(t, f, "viewer@example.com")
+
+		// members leave by themselves, only owners remove others
+		if err := s.RemoveWorkspaceMember(f.viewerCtx, editor.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.RemoveWorkspaceMember(f.viewerCtx, viewer.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveWorkspaceMember(f.ownerCtx, editor.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.FindWorkspaceMembers(f.editorCtx, f.workspace.ID); suss.ErrorCode(err) != suss.EUNAUTHORIZED {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// DailyCount represents the number of events on a day, in UTC.
+type DailyCount struct {
+	Date  time.Time `json:"date"`
+	Count int       `json:"count"`
+}
+
+// InstanceStats summarizes the activity of the whole instance.
+type InstanceStats struct {
+	ShortURLs int `json:"short_urls"`
+	Clicks    int `json:"clicks"`
+	Users     int `json:"users"`
+
+	// Short urls created and clicks recorded on each of the last days, oldest
+	// first. Days without any are included with a zero count.
+	ShortURLsPerDay []DailyCount `json:"short_urls_per_day"`
+	ClicksPerDay    []DailyCount `json:"clicks_per_day"`
+}
+
+// StatsService reports on the instance as a whole. Only administrators can
+// read instance stats.
+type StatsService interface {
+	// FindInstanceStats returns the totals of the instance along with the
+	// daily counts of the given number of days, including today.
+	FindInstanceStats(ctx context.Context, days int) (*InstanceStats, error)
+}
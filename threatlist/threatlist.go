@@ -0,0 +1,262 @@
+// Package threatlist implements suss.URLChecker against a local threat list,
+// such as one synced from the Safe Browsing Update API by an external job.
+//
+// The list holds one entry per line, the threat type followed by a hex
+// encoded SHA-256 hash prefix of 4 to 32 bytes. Blank lines and lines
+// starting with "#" are ignored:
+//
+//	# threat type, hash prefix
+//	MALWARE 1a2b3c4d
+//	SOCIAL_ENGINEERING 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//
+// Urls are hashed as Safe Browsing url expressions, so an entry for
+// "evil.example/" matches every url on evil.example and its subdomains.
+//
+// A full 32 byte hash is a confirmed match. Shorter prefixes also match
+// unrelated urls, so a prefix match is confirmed with the FullHashFinder, if
+// any. Without one, the url is flagged with the threat type prefixed by
+// Unconfirmed until the list holds its full hash.
+package threatlist
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits of the url expressions checked, as defined by Safe Browsing.
+const (
+	MaxHostSuffixes = 5
+	MaxPathPrefixes = 6
+)
+
+// Unconfirmed prefixes the threat type of urls matching a hash prefix which
+// could not be confirmed, e.g. "UNCONFIRMED_MALWARE".
+const Unconfirmed = "UNCONFIRMED_"
+
+// FullHashFinder returns the full hashes listed for a hash prefix along with
+// their threat types, such as with the fullHashes.find method of the Safe
+// Browsing API.
+type FullHashFinder interface {
+	FindFullHashes(ctx context.Context, prefix []byte) (map[[sha256.Size]byte]string, error)
+}
+
+// URLChecker matches urls against the threat list at Path. The list is read
+// again whenever the file changes.
+type URLChecker struct {
+	mu       sync.RWMutex
+	modTime  time.Time
+	size     int64
+	prefixes map[string]string // hash prefix to threat type
+	lengths  []int             // distinct prefix lengths, shortest first
+
+	Path string
+
+	// Confirms matches of prefixes shorter than a full hash, if set.
+	FullHashFinder FullHashFinder
+}
+
+func NewURLChecker(path string) *URLChecker {
+	return &URLChecker{
+		Path: path,
+	}
+}
+
+// Open reads the threat list for the first time.
+func (c *URLChecker) Open() error {
+	if c.Path == "" {
+		return fmt.Errorf("threat list path required")
+	}
+	return c.reload()
+}
+
+// CheckURL returns the threat type of the first list entry matching rawURL.
+// Prefix matches are confirmed with the FullHashFinder, or returned as
+// Unconfirmed without one.
+func (c *URLChecker) CheckURL(ctx context.Context, rawURL string) (string, error) {
+	// keep using the previous list while the file is being replaced
+	if err := c.reloadIfChanged(); err != nil {
+		log.Printf("cannot reload threat list: %s", err)
+	}
+
+	var unconfirmed string
+	for _, expr := range Expressions(rawURL) {
+		sum := sha256.Sum256([]byte(expr))
+		prefix, threat := c.match(sum)
+		if threat == "" {
+			continue
+		} else if len(prefix) == sha256.Size {
+			return threat, nil
+		}
+
+		if c.FullHashFinder == nil {
+			if unconfirmed == "" {
+				unconfirmed = Unconfirmed + threat
+			}
+			continue
+		}
+
+		hashes, err := c.FullHashFinder.FindFullHashes(ctx, prefix)
+		if err != nil {
+			return "", fmt.Errorf("cannot find full hashes: %w", err)
+		} else if threat, ok := hashes[sum]; ok {
+			return threat, nil
+		}
+	}
+	return unconfirmed, nil
+}
+
+// match returns the longest list prefix of sum and its threat type, or an
+// empty threat type if none matches.
+func (c *URLChecker) match(sum [sha256.Size]byte) ([]byte, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, n := range slices.Backward(c.lengths) {
+		if threat, ok := c.prefixes[string(sum[:n])]; ok {
+			return sum[:n], threat
+		}
+	}
+	return nil, ""
+}
+
+// reloadIfChanged reads the list again if its size or modification time changed.
+func (c *URLChecker) reloadIfChanged() error {
+	fi, err := os.Stat(c.Path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	changed := !fi.ModTime().Equal(c.modTime) || fi.Size() != c.size
+	c.mu.RUnlock()
+
+	if !changed {
+		return nil
+	}
+	return c.reload()
+}
+
+func (c *URLChecker) reload() error {
+	f, err := os.Open(c.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	prefixes, lengths := make(map[string]string), []int{}
+	scanner := bufio.NewScanner(f)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: expected a threat type and a hash prefix", c.Path, lineno)
+		}
+		prefix, err := hex.DecodeString(fields[1])
+		if err != nil || len(prefix) < 4 || len(prefix) > sha256.Size {
+			return fmt.Errorf("%s:%d: invalid hash prefix %q", c.Path, lineno, fields[1])
+		}
+
+		prefixes[string(prefix)] = fields[0]
+		if !slices.Contains(lengths, len(prefix)) {
+			lengths = append(lengths, len(prefix))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	slices.Sort(lengths)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefixes, c.lengths = prefixes, lengths
+	c.modTime, c.size = fi.ModTime(), fi.Size()
+
+	return nil
+}
+
+// Expressions returns the host suffix & path prefix combinations of rawURL
+// which are hashed and looked up in threat lists, e.g. "a.b.example/1/2.html?q",
+// "a.b.example/1/", "b.example/" and so on. Returns nil for invalid urls.
+func Expressions(rawURL string) []string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+
+	var exprs []string
+	for _, host := range hostSuffixes(u.Hostname()) {
+		for _, path := range pathPrefixes(u) {
+			exprs = append(exprs, host+path)
+		}
+	}
+	return exprs
+}
+
+// hostSuffixes returns the exact host followed by up to four suffixes formed
+// from its last five components, skipping the top level domain alone.
+func hostSuffixes(host string) []string {
+	host = strings.Trim(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+
+	suffixes := []string{host}
+	parts := strings.Split(host, ".")
+	if len(parts) > MaxHostSuffixes {
+		parts = parts[len(parts)-MaxHostSuffixes:]
+	}
+	for i := 0; i < len(parts)-1; i++ {
+		if suffix := strings.Join(parts[i:], "."); suffix != host {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	return suffixes
+}
+
+// pathPrefixes returns the exact path with and without the query string,
+// followed by "/" and up to three directory prefixes of the path.
+func pathPrefixes(u *url.URL) []string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	var prefixes []string
+	add := func(v string) {
+		if !slices.Contains(prefixes, v) && len(prefixes) < MaxPathPrefixes {
+			prefixes = append(prefixes, v)
+		}
+	}
+
+	if u.RawQuery != "" {
+		add(path + "?" + u.RawQuery)
+	}
+	add(path)
+	add("/")
+
+	dirs := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i < len(dirs) && i <= 3; i++ {
+		add("/" + strings.Join(dirs[:i], "/") + "/")
+	}
+	return prefixes
+}
@@ -0,0 +1,125 @@
+package threatlist_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heyjorgedev/suss/threatlist"
+)
+
+// MustWriteList writes a threat list with the given contents to a temporary file.
+func MustWriteList(tb testing.TB, contents string) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "threats.txt")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// MustOpenURLChecker returns an open URLChecker for a list with contents.
+func MustOpenURLChecker(tb testing.TB, contents string) *threatlist.URLChecker {
+	tb.Helper()
+	c := threatlist.NewURLChecker(MustWriteList(tb, contents))
+	if err := c.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// FullHashFinder returns its full hashes for any prefix of them.
+type FullHashFinder map[[sha256.Size]byte]string
+
+func (f FullHashFinder) FindFullHashes(ctx context.Context, prefix []byte) (map[[sha256.Size]byte]string, error) {
+	hashes := make(map[[sha256.Size]byte]string)
+	for hash, threat := range f {
+		if string(hash[:len(prefix)]) == string(prefix) {
+			hashes[hash] = threat
+		}
+	}
+	return hashes, nil
+}
+
+// hash returns the hex encoded prefix of n bytes of the hash of expr.
+func hash(expr string, n int) string {
+	sum := sha256.Sum256([]byte(expr))
+	return hex.EncodeToString(sum[:n])
+}
+
+func TestURLChecker_CheckURL(t *testing.T) {
+	t.Run("FullHash", func(t *testing.T) {
+		c := MustOpenURLChecker(t, "# test list\nMALWARE "+hash("evil.example/", sha256.Size)+"\n")
+		if threat, err := c.CheckURL(context.Background(), "https://www.evil.example/download?id=1"); err != nil {
+			t.Fatal(err)
+		} else if threat != "MALWARE" {
+			t.Fatalf("threat=%q", threat)
+		}
+
+		if threat, err := c.CheckURL(context.Background(), "https://example.com/"); err != nil {
+			t.Fatal(err)
+		} else if threat != "" {
+			t.Fatalf("threat=%q", threat)
+		}
+	})
+
+	t.Run("PrefixUnconfirmed", func(t *testing.T) {
+		// without a way to confirm, a prefix match is flagged as unconfirmed
+		c := MustOpenURLChecker(t, "MALWARE "+hash("evil.example/", 4)+"\n")
+		if threat, err := c.CheckURL(context.Background(), "https://evil.example/"); err != nil {
+			t.Fatal(err)
+		} else if threat != threatlist.Unconfirmed+"MALWARE" {
+			t.Fatalf("threat=%q", threat)
+		}
+	})
+
+	t.Run("PrefixConfirmed", func(t *testing.T) {
+		c := MustOpenURLChecker(t, "SOCIAL_ENGINEERING "+hash("evil.example/", 4)+"\n")
+		c.FullHashFinder = FullHashFinder{sha256.Sum256([]byte("evil.example/")): "SOCIAL_ENGINEERING"}
+		if threat, err := c.CheckURL(context.Background(), "https://evil.example/login"); err != nil {
+			t.Fatal(err)
+		} else if threat != "SOCIAL_ENGINEERING" {
+			t.Fatalf("threat=%q", threat)
+		}
+	})
+
+	t.Run("PrefixNotConfirmed", func(t *testing.T) {
+		// the prefix is shared with another url, which is the listed one
+		c := MustOpenURLChecker(t, "MALWARE "+hash("evil.example/", 4)+"\n")
+		c.FullHashFinder = FullHashFinder{}
+		if threat, err := c.CheckURL(context.Background(), "https://evil.example/"); err != nil {
+			t.Fatal(err)
+		} else if threat != "" {
+			t.Fatalf("threat=%q", threat)
+		}
+	})
+
+	t.Run("FullHashOverPrefix", func(t *testing.T) {
+		// a full hash confirms the match even if a prefix of it is listed too
+		c := MustOpenURLChecker(t, "MALWARE "+hash("evil.example/", 4)+"\nMALWARE "+hash("evil.example/", sha256.Size)+"\n")
+		if threat, err := c.CheckURL(context.Background(), "https://evil.example/"); err != nil {
+			t.Fatal(err)
+		} else if threat != "MALWARE" {
+			t.Fatalf("threat=%q", threat)
+		}
+	})
+}
+
+func TestURLChecker_Open(t *testing.T) {
+	t.Run("ErrPrefixTooShort", func(t *testing.T) {
+		c := threatlist.NewURLChecker(MustWriteList(t, "MALWARE "+hash("evil.example/", 3)+"\n"))
+		if err := c.Open(); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("ErrHashTooLong", func(t *testing.T) {
+		c := threatlist.NewURLChecker(MustWriteList(t, "MALWARE "+hash("evil.example/", sha256.Size)+"00\n"))
+		if err := c.Open(); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
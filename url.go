@@ -0,0 +1,122 @@
+package suss
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// URLPolicy describes which destination urls are accepted by short urls.
+type URLPolicy struct {
+	// Allowed schemes, compared case-insensitively.
+	Schemes []string
+
+	// Maximum length of the normalized url.
+	MaxLength int
+
+	// If true, the "#fragment" part of the url is removed.
+	StripFragment bool
+
+	// Hosts serving the shortener itself. Links to these hosts are rejected
+	// to prevent redirect loops. Links to the host a request is sent to are
+	// rejected even if unset, see CheckRequestHost.
+	Hosts []string
+}
+
+// DefaultURLPolicy is the policy used by ShortURL.Validate. It is usually
+// configured once on startup.
+var DefaultURLPolicy = URLPolicy{
+	Schemes:   []string{"http", "https"},
+	MaxLength: 2048,
+}
+
+// Normalize validates rawURL against the policy and returns its normalized
+// form, with a lowercase scheme and host and internationalized domain names
+// converted to punycode.
+func (p *URLPolicy) Normalize(rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", Errorf(EINVALID, "Long url required.")
+	} else if p.MaxLength > 0 && len(rawURL) > p.MaxLength {
+		return "", Errorf(EINVALID, "Long url must be at most %d characters.", p.MaxLength)
+	}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", Errorf(EINVALID, "Long url is not a valid url.")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !p.allowsScheme(u.Scheme) {
+		return "", Errorf(EINVALID, "Long url must use one of the following schemes: %s.", strings.Join(p.Schemes, ", "))
+	} else if u.Opaque != "" || u.Hostname() == "" {
+		return "", Errorf(EINVALID, "Long url must include a host.")
+	} else if u.User != nil {
+		return "", Errorf(EINVALID, "Long url must not include credentials.")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) == nil {
+		if host, err = idna.Lookup.ToASCII(host); err != nil {
+			return "", Errorf(EINVALID, "Long url host is not a valid domain name.")
+		}
+	}
+	if p.isOwnHost(host) {
+		return "", Errorf(EINVALID, "Long url must not point to this shortener.")
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if p.StripFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	normalized := u.String()
+	if p.MaxLength > 0 && len(normalized) > p.MaxLength {
+		return "", Errorf(EINVALID, "Long url must be at most %d characters.", p.MaxLength)
+	}
+	return normalized, nil
+}
+
+func (p *URLPolicy) allowsScheme(scheme string) bool {
+	for _, v := range p.Schemes {
+		if strings.EqualFold(v, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *URLPolicy) isOwnHost(host string) bool {
+	for _, v := range p.Hosts {
+		if h, _, err := net.SplitHostPort(v); err == nil {
+			v = h
+		}
+		if strings.EqualFold(strings.Trim(v, "[]"), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRequestHost returns an error if host is the host the request in ctx was
+// sent to, so links cannot loop back to the shortener when URLPolicy.Hosts is
+// not configured.
+func CheckRequestHost(ctx context.Context, host string) error {
+	info := RequestInfoFromContext(ctx)
+	if info == nil || info.Host == "" {
+		return nil
+	}
+	p := URLPolicy{Hosts: []string{info.Host}}
+	if p.isOwnHost(host) {
+		return Errorf(EINVALID, "Long url must not point to this shortener.")
+	}
+	return nil
+}
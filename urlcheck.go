@@ -0,0 +1,12 @@
+package suss
+
+import "context"
+
+// URLChecker screens destination urls for malware, phishing and other threats
+// before they are shortened. Existing short urls are checked again
+// periodically as threat lists are updated.
+type URLChecker interface {
+	// CheckURL returns the type of threat url is listed for, such as
+	// "MALWARE" or "SOCIAL_ENGINEERING", or an empty string if none.
+	CheckURL(ctx context.Context, url string) (string, error)
+}
@@ -0,0 +1,74 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// User represents a registered account. Links created while signed in are
+// owned by the user and can be managed without their secret key.
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+
+	// Password is only used to set a new password and is never stored or
+	// returned. Users without a PasswordHash cannot sign in with a password.
+	Password     string `json:"-"`
+	PasswordHash string `json:"-"`
+
+	// Administrators manage site wide settings, such as the domain rules.
+	IsAdmin bool `json:"is_admin"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate returns an error if the user contains invalid fields. The email is
+// normalized in place.
+func (u *User) Validate() (err error) {
+	if u.Name == "" {
+		return Errorf(EINVALID, "Name required.")
+	} else if len(u.Name) > 100 {
+		return Errorf(EINVALID, "Name must be at most 100 characters.")
+	}
+	if u.Email == "" {
+		return Errorf(EINVALID, "Email required.")
+	} else if u.Email, err = NormalizeEmail(u.Email); err != nil {
+		return err
+	}
+	return nil
+}
+
+type UserFilter struct {
+	ID    *int    `json:"id"`
+	Email *string `json:"email"`
+
+	// Restrict to a subset of the results, oldest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// UserUpdate represents a set of fields to be updated via Update().
+type UserUpdate struct {
+	Name     *string `json:"name"`
+	Email    *string `json:"email"`
+	Password *string `json:"password"`
+
+	// Only administrators, or maintenance commands running without a user,
+	// can grant or revoke the admin role.
+	IsAdmin *bool `json:"is_admin"`
+}
+
+type UserService interface {
+	FindUserByID(ctx context.Context, id int) (*User, error)
+	FindUsers(ctx context.Context, filter UserFilter) ([]*User, int, error)
+
+	// Authenticate returns the user with email if password matches, or an
+	// EUNAUTHORIZED error otherwise.
+	Authenticate(ctx context.Context, email, password string) (*User, error)
+
+	Create(ctx context.Context, user *User) error
+	Update(ctx context.Context, id int, upd UserUpdate) (*User, error)
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,113 @@
+package suss
+
+import (
+	"context"
+	"time"
+)
+
+// Workspace roles, from most to least privileged. Owners manage members,
+// editors create & change links and viewers can only see them.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+// workspaceRoleRanks orders roles so a higher rank includes lower ones.
+var workspaceRoleRanks = map[string]int{
+	WorkspaceRoleViewer: 1,
+	WorkspaceRoleEditor: 2,
+	WorkspaceRoleOwner:  3,
+}
+
+// IsWorkspaceRole returns true if role is a known workspace role.
+func IsWorkspaceRole(role string) bool {
+	_, ok := workspaceRoleRanks[role]
+	return ok
+}
+
+// HasWorkspaceRole returns true if role grants at least the permissions of min.
+func HasWorkspaceRole(role, min string) bool {
+	return IsWorkspaceRole(role) && workspaceRoleRanks[role] >= workspaceRoleRanks[min]
+}
+
+// Workspace represents a set of short urls shared by its members.
+type Workspace struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+
+	// Role of the current user in the workspace, set when the workspace is
+	// returned by WorkspaceService.
+	Role string `json:"role,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (w *Workspace) Validate() error {
+	if w.Name == "" {
+		return Errorf(EINVALID, "Workspace name required.")
+	} else if len(w.Name) > 100 {
+		return Errorf(EINVALID, "Workspace name must be at most 100 characters.")
+	}
+	return nil
+}
+
+// WorkspaceMember represents the role of a user in a workspace.
+type WorkspaceMember struct {
+	ID          int    `json:"id"`
+	WorkspaceID int    `json:"workspace_id"`
+	UserID      int    `json:"user_id"`
+	User        *User  `json:"user"`
+	Role        string `json:"role"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (m *WorkspaceMember) Validate() error {
+	if m.WorkspaceID == 0 {
+		return Errorf(EINVALID, "Workspace required.")
+	} else if m.UserID == 0 {
+		return Errorf(EINVALID, "User required.")
+	} else if !IsWorkspaceRole(m.Role) {
+		return Errorf(EINVALID, "Invalid role %q.", m.Role)
+	}
+	return nil
+}
+
+// WorkspaceFilter filters the workspaces of the current user.
+type WorkspaceFilter struct {
+	ID *int `json:"id"`
+
+	// Restrict to a subset of the results, oldest first.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// WorkspaceService manages workspaces and their members. Every method acts
+// on behalf of the user in the context and returns an EUNAUTHORIZED error if
+// their role does not allow it.
+type WorkspaceService interface {
+	// FindWorkspaceByID returns a workspace the current user is a member of.
+	FindWorkspaceByID(ctx context.Context, id int) (*Workspace, error)
+
+	// FindWorkspaces returns the workspaces the current user is a member of.
+	FindWorkspaces(ctx context.Context, filter WorkspaceFilter) ([]*Workspace, int, error)
+
+	// Create creates a workspace owned by the current user.
+	Create(ctx context.Context, workspace *Workspace) error
+	Delete(ctx context.Context, id int) error
+
+	FindWorkspaceMembers(ctx context.Context, workspaceID int) ([]*WorkspaceMember, error)
+
+	// InviteWorkspaceMember adds the registered user with email to a
+	// workspace with role. Only owners can manage members.
+	InviteWorkspaceMember(ctx context.Context, workspaceID int, email, role string) (*WorkspaceMember, error)
+
+	// UpdateWorkspaceMember changes the role of a member. A workspace must
+	// always keep at least one owner.
+	UpdateWorkspaceMember(ctx context.Context, id int, role string) (*WorkspaceMember, error)
+
+	// RemoveWorkspaceMember removes a member. Members can remove themselves.
+	RemoveWorkspaceMember(ctx context.Context, id int) error
+}